@@ -0,0 +1,52 @@
+package csv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// boolTokens are the words used to write booleans
+type boolTokens struct {
+	trueTokens  []string
+	falseTokens []string
+}
+
+// parseBoolTag parses the value of a "bool" tag option, a true and false token separated by '|'
+func parseBoolTag(tag string) (*boolTokens, error) {
+	tokens := strings.Split(tag, "|")
+	if len(tokens) != 2 {
+		return nil, fmt.Errorf("bool option %q must be a true and false token separated by '|'", tag)
+	}
+	return &boolTokens{
+		trueTokens:  []string{tokens[0]},
+		falseTokens: []string{tokens[1]},
+	}, nil
+}
+
+// parse matches value against the tokens, ignoring case
+func (b *boolTokens) parse(value string) (bool, error) {
+	for _, token := range b.trueTokens {
+		if strings.EqualFold(value, token) {
+			return true, nil
+		}
+	}
+	for _, token := range b.falseTokens {
+		if strings.EqualFold(value, token) {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("invalid boolean %q - expected one of %q or %q", value, b.trueTokens, b.falseTokens)
+}
+
+// format returns the first true or false token, falling back to strconv.FormatBool if there are none
+func (b *boolTokens) format(value bool) string {
+	tokens := b.falseTokens
+	if value {
+		tokens = b.trueTokens
+	}
+	if len(tokens) == 0 {
+		return strconv.FormatBool(value)
+	}
+	return tokens[0]
+}
@@ -2,30 +2,63 @@ package csv
 
 import (
 	"bytes"
+	"database/sql"
+	"encoding"
 	rawcsv "encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
 )
 
-// UnmarshalCSV describes how CSV should handle types that aren't strings or built in
+// UnmarshalCSV describes how CSV should handle types that aren't strings or built in.
+// Types that implement sql.Scanner or encoding.TextUnmarshaler are also supported, but UnmarshalCSV takes priority
 type UnmarshalCSV interface {
 	UnmarshalCSV(string) error
 }
 
 var unmarshalCSV = reflect.TypeOf((*UnmarshalCSV)(nil)).Elem()
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 
 // Decoder reads and decodes a csv into an array from an input stream
 type Decoder struct {
-	reader *rawcsv.Reader
+	reader   *rawcsv.Reader
+	lenient  bool
+	ragged   bool
+	strict   bool // disallow unknown columns
+	required bool // require all columns
+	zero     bool // decode empty cells as zero values
+
+	nullTokens  []string       // cells decoded as nil pointers or zero values
+	timeLayouts []string       // layouts for time.Time fields without a format tag
+	location    *time.Location // time zone for time.Time fields without a loc tag
+	numbers     *numberFormat  // locale for number fields without a decimal or group tag
+	bools       *boolTokens    // words for bool fields without a bool tag
+	prefixSep   string         // separator between the prefix and name of inlined columns
+	listSep     string         // separator between the items of slice fields without a sep tag
+
+	header []string     // header row, read once before the first record
+	ty     reflect.Type // struct type the header is currently mapped to
+	fields []field      // columns of the mapped struct type
+	h2f    []int        // headers to fields
+	groups [][]int      // fields to their numbered headers in order, for fields with a column pattern
+	ptrs   []structPtr  // embedded and inlined struct pointers of the mapped struct type
 }
 
 // NewDecoder creates a new decoder from the given reader
 func NewDecoder(r io.Reader) *Decoder {
 	reader := rawcsv.NewReader(r)
-	return &Decoder{reader}
+	// Record lengths are checked against the header by the decoder itself
+	reader.FieldsPerRecord = -1
+	return &Decoder{
+		reader:      reader,
+		timeLayouts: []string{time.RFC3339},
+		prefixSep:   ".",
+		listSep:     "|",
+	}
 }
 
 // SetDelimiter character for the csv reader
@@ -52,6 +85,127 @@ func (d *Decoder) DisableTrimLeadingSpace() {
 	d.reader.TrimLeadingSpace = false
 }
 
+// DisallowUnknownColumns makes the decoder return an error when the header row
+// contains a column that does not match any field of the struct being decoded into
+func (d *Decoder) DisallowUnknownColumns() {
+	d.strict = true
+}
+
+// AllowUnknownColumns makes the decoder ignore any header columns that do not match a field of the struct.
+// This is the default
+func (d *Decoder) AllowUnknownColumns() {
+	d.strict = false
+}
+
+// RequireAllColumns makes every field of the struct required,
+// as if they were all tagged with the "required" option
+func (d *Decoder) RequireAllColumns() {
+	d.required = true
+}
+
+// AllowMissingColumns only requires the fields tagged with the "required" option to be present in the header.
+// This is the default
+func (d *Decoder) AllowMissingColumns() {
+	d.required = false
+}
+
+// ZeroEmpty makes empty cells decode as the zero value of their field,
+// instead of failing to parse for types such as numbers, booleans and times.
+// A field can opt in to this on its own with the "zeroempty" tag option
+func (d *Decoder) ZeroEmpty() {
+	d.zero = true
+}
+
+// DisableZeroEmpty makes empty cells decode like any other value, so only types that accept an empty string succeed.
+// This is the default
+func (d *Decoder) DisableZeroEmpty() {
+	d.zero = false
+}
+
+// SetNullTokens sets the cell values that represent a missing value, such as "NULL" or `\N`.
+// These are decoded as a nil pointer for pointer fields, and as the zero value for any other field.
+// Empty cells are always decoded as a nil pointer for pointer fields
+func (d *Decoder) SetNullTokens(tokens ...string) {
+	d.nullTokens = tokens
+}
+
+// SetTimeFormat sets the layouts used to parse time.Time fields, see time.Parse.
+// Each layout is tried in turn until one succeeds. The default is time.RFC3339.
+// A field can override this with the "format" tag option, separating multiple layouts with '|'.
+// Tag options can't contain commas, so layouts like "Jan 2, 2006" have to be set here instead,
+// though the tag option does accept the names of the time package's layouts, such as RFC1123.
+// Calling SetTimeFormat with no layouts restores the default
+func (d *Decoder) SetTimeFormat(layouts ...string) {
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	d.timeLayouts = layouts
+}
+
+// SetLocation sets the time zone used to parse time.Time values that do not specify one, see time.ParseInLocation.
+// The default is UTC. A field can override this with the "loc" tag option, e.g. `csv:"created,loc=Europe/London"`
+func (d *Decoder) SetLocation(loc *time.Location) {
+	d.location = loc
+}
+
+// SetNumberFormat makes number fields parse using the given decimal and grouping (thousands) separators,
+// e.g. SetNumberFormat(',', '.') for "1.234,56". A grouping of 0 means numbers are not grouped.
+// Currency symbols and spaces are ignored, and numbers in parentheses are read as negative.
+// Decode fails if the decimal and grouping separators are the same.
+// A field can override this with the "decimal" and "group" tag options, e.g. `csv:"amount,decimal=comma,group=dot"`
+func (d *Decoder) SetNumberFormat(decimal, grouping rune) {
+	d.numbers = newNumberFormat(decimal, grouping)
+}
+
+// SetBoolTokens sets the words that bool fields are parsed from, ignoring case,
+// e.g. SetBoolTokens([]string{"yes", "y"}, []string{"no", "n"}).
+// By default strconv.ParseBool is used. A field can override this with the "bool" tag option,
+// a true and false token separated by '|', e.g. `csv:"active,bool=Y|N"`
+func (d *Decoder) SetBoolTokens(trueTokens, falseTokens []string) {
+	d.bools = &boolTokens{trueTokens: trueTokens, falseTokens: falseTokens}
+}
+
+// SetPrefixSeparator sets the separator between the name of a field with the "inline" option
+// and the names of its nested fields. The default is ".", so `csv:"billing,inline"` gives columns like "billing.street"
+func (d *Decoder) SetPrefixSeparator(sep string) {
+	d.prefixSep = sep
+}
+
+// SetListSeparator sets the separator between the items of slice fields, which are decoded from a single cell.
+// A backslash escapes a separator or backslash within an item. The default is "|".
+// Decoding fails if the separator is empty or contains a backslash.
+// An empty cell decodes as a nil slice, so nil, empty and single empty string slices can't be told apart.
+// A field can override this with the "sep" tag option, e.g. `csv:"tags,sep=;"`
+func (d *Decoder) SetListSeparator(sep string) {
+	d.listSep = sep
+}
+
+// AllowRaggedRows lets rows be shorter than the header row.
+// The fields for any missing trailing cells are left as zero values.
+// Rows with more cells than the header are still rejected with a FieldCountError
+func (d *Decoder) AllowRaggedRows() {
+	d.ragged = true
+}
+
+// DisallowRaggedRows makes any row with a different number of cells to the header row fail with a FieldCountError.
+// This is the default
+func (d *Decoder) DisallowRaggedRows() {
+	d.ragged = false
+}
+
+// Lenient makes Decode skip rows that fail to decode instead of stopping at the first bad row.
+// Once the whole input has been read, every failure is returned together as RowErrors,
+// and v holds all the rows that decoded successfully.
+func (d *Decoder) Lenient() {
+	d.lenient = true
+}
+
+// DisableLenient makes Decode stop and return the error of the first row that fails to decode.
+// This is the default
+func (d *Decoder) DisableLenient() {
+	d.lenient = false
+}
+
 // Decode decodes the reader into the value v
 // v must be an array of structs, where the struct field names (or tags) define the csv header name to decode from
 // Will decode most built in types, otherwise it will use the FromString interface to decode
@@ -62,6 +216,10 @@ func (d *Decoder) Decode(v interface{}) error {
 		return fmt.Errorf("Decode: could not decode into type %v - must be a pointer", value.Type())
 	}
 
+	if err := d.checkOptions(); err != nil {
+		return err
+	}
+
 	ty := value.Type().Elem()
 
 	switch ty.Kind() {
@@ -72,68 +230,355 @@ func (d *Decoder) Decode(v interface{}) error {
 			return fmt.Errorf("Decode: could not decode into type %v - expected a slice of structs", ty)
 		}
 
-		fields := make([]string, 0, elem.NumField())
-		for i := 0; i < cap(fields); i++ {
-			field := elem.Field(i)
+		if err := d.mapHeader(elem); err != nil {
+			return err
+		}
 
-			column := field.Tag.Get("csv")
-			if column == "" {
-				column = field.Name
+		var rowErrs RowErrors
+		for {
+			record := reflect.New(elem).Elem()
+			err := d.decodeRecord(record)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				if d.lenient && recoverable(err) {
+					rowErrs = append(rowErrs, err)
+					continue
+				}
+				return err
 			}
 
-			if !validUnmarshalType(field.Type) {
-				return fmt.Errorf("Decode: %v is not a valid field type - try implement UnmarshalCSV for it", field.Type)
-			}
+			value.Elem().Set(reflect.Append(value.Elem(), record))
+		}
 
-			fields = append(fields, column)
+		if len(rowErrs) > 0 {
+			return rowErrs
 		}
+	default:
+		return fmt.Errorf("Decode: could not decode into type %v", ty)
+	}
+
+	return nil
+}
 
-		headers, err := d.reader.Read()
+// DecodeRecord decodes the next record of the reader into the value v
+// v must be a pointer to a struct, where the struct field names (or tags) define the csv header name to decode from.
+// The header row is read on the first call, each call after that decodes a single row.
+// Returns io.EOF once there are no more records to read
+func (d *Decoder) DecodeRecord(v interface{}) error {
+	value := reflect.ValueOf(v)
+
+	if value.Kind() != reflect.Ptr || value.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeRecord: could not decode into type %T - must be a pointer to a struct", v)
+	}
+	if value.IsNil() {
+		return fmt.Errorf("DecodeRecord: could not decode into nil %T", v)
+	}
+
+	if err := d.checkOptions(); err != nil {
+		return err
+	}
+	if err := d.mapHeader(value.Type().Elem()); err != nil {
+		return err
+	}
+
+	return d.decodeRecord(value.Elem())
+}
+
+// checkOptions reports an error for any invalid settings, like encoding/csv does for an invalid Comma
+func (d *Decoder) checkOptions() error {
+	if err := validListSeparator(d.listSep); err != nil {
+		return fmt.Errorf("Decode: %w", err)
+	}
+	if d.numbers != nil {
+		if err := d.numbers.validate(); err != nil {
+			return fmt.Errorf("Decode: %w", err)
+		}
+	}
+	return nil
+}
+
+// mapHeader builds the mapping from the header row to the fields of the struct type ty.
+// The header row is read from the reader the first time this is called
+func (d *Decoder) mapHeader(ty reflect.Type) error {
+	if d.ty == ty {
+		return nil
+	}
+
+	fields, err := typeFields(ty, d.prefixSep)
+	if err != nil {
+		return fmt.Errorf("Decode: %w", err)
+	}
+	for _, field := range fields {
+		if !validUnmarshalType(field.itemType()) {
+			return fmt.Errorf("Decode: %v is not a valid field type - try implement UnmarshalCSV for it", field.itemType())
+		}
+	}
+
+	if d.header == nil {
+		header, err := d.reader.Read()
 		if err != nil {
 			return err
 		}
+		d.header = header
+	}
+
+	type numbered struct {
+		number int
+		header int
+	}
+
+	found := make([]bool, len(fields))
+	numberedHeaders := make([][]numbered, len(fields))
+	h2f := make([]int, len(d.header))
+	for i, header := range d.header {
+		h2f[i] = -1
+		for j, field := range fields {
+			if !field.pattern && header == field.name {
+				h2f[i] = j
+				found[j] = true
+				break
+			}
+		}
 
-		h2f := map[int]int{} // headers to fields
-		for i, header := range headers {
+		if h2f[i] == -1 {
 			for j, field := range fields {
-				if header == field {
+				if !field.pattern {
+					continue
+				}
+				if n, ok := matchPattern(field.name, header); ok {
 					h2f[i] = j
-					goto next_header
+					found[j] = true
+					numberedHeaders[j] = append(numberedHeaders[j], numbered{n, i})
+					break
 				}
 			}
+		}
 
+		if h2f[i] == -1 && d.strict {
 			return fmt.Errorf("Decode: field for header[%s] was not found", header)
+		}
+	}
 
-		next_header:
+	groups := make([][]int, len(fields))
+	for j, headers := range numberedHeaders {
+		if len(headers) == 0 {
+			continue
+		}
+		if fields[j].typ.Kind() == reflect.Array && len(headers) > fields[j].typ.Len() {
+			return fmt.Errorf("Decode: found %d columns for field %s, but %v can only hold %d", len(headers), fields[j].goName, fields[j].typ, fields[j].typ.Len())
 		}
 
-		for {
-			row, err := d.reader.Read()
-			if err == io.EOF {
+		sort.SliceStable(headers, func(a, b int) bool {
+			return headers[a].number < headers[b].number
+		})
+		for _, h := range headers {
+			groups[j] = append(groups[j], h.header)
+		}
+	}
+
+	var missing []string
+	for j, field := range fields {
+		if !found[j] && (field.required || d.required) {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingColumnsError{Columns: missing}
+	}
+
+	d.ty, d.fields, d.h2f, d.groups = ty, fields, h2f, groups
+	d.ptrs = structPtrs(ty, fields, h2f)
+	return nil
+}
+
+// structPtr is an embedded or inlined pointer to a struct, which is left nil when all of its cells are empty,
+// so that decoding what the Encoder wrote for a nil pointer gives a nil pointer back
+type structPtr struct {
+	index   []int // index of the pointer field
+	headers []int // headers mapped to fields within the struct
+}
+
+// structPtrs finds the struct pointers that contain the fields mapped to by h2f
+func structPtrs(ty reflect.Type, fields []field, h2f []int) []structPtr {
+	var ptrs []structPtr
+	for i, j := range h2f {
+		if j == -1 {
+			continue
+		}
+
+		index := fields[j].index
+		t := ty
+		for depth := 0; depth < len(index)-1; depth++ {
+			if t = t.Field(index[depth]).Type; t.Kind() != reflect.Ptr {
+				continue
+			}
+			t = t.Elem()
+
+			k := 0
+			for k < len(ptrs) && !reflect.DeepEqual(ptrs[k].index, index[:depth+1]) {
+				k++
+			}
+			if k == len(ptrs) {
+				ptrs = append(ptrs, structPtr{index: index[:depth+1]})
+			}
+			ptrs[k].headers = append(ptrs[k].headers, i)
+		}
+	}
+	return ptrs
+}
+
+// nilPtrs returns the index of every struct pointer whose cells in row are all empty or null
+func (d *Decoder) nilPtrs(row []string) [][]int {
+	var nils [][]int
+	for _, p := range d.ptrs {
+		empty := true
+		for _, i := range p.headers {
+			if i < len(row) && row[i] != "" && !d.isNull(row[i]) {
+				empty = false
 				break
-			} else if err != nil {
-				return err
 			}
+		}
+		if empty {
+			nils = append(nils, p.index)
+		}
+	}
+	return nils
+}
 
-			record := reflect.New(elem)
-			for i, column := range row {
-				field := record.Elem().Field(h2f[i])
+// decodeRecord reads the next row and decodes it into record, which must be a value of the currently mapped struct type
+func (d *Decoder) decodeRecord(record reflect.Value) error {
+	row, err := d.reader.Read()
+	if err != nil {
+		return err
+	}
 
-				if err := setField(field, column); err != nil {
-					return err
-				}
+	if len(row) > len(d.header) || (len(row) < len(d.header) && !d.ragged) {
+		line, _ := d.reader.FieldPos(0)
+		return &FieldCountError{
+			Line:     line,
+			Expected: len(d.header),
+			Got:      len(row),
+		}
+	}
+
+	record.Set(reflect.Zero(record.Type()))
+	nils := d.nilPtrs(row)
+	for i, column := range row {
+		if d.h2f[i] == -1 || d.groups[d.h2f[i]] != nil {
+			continue
+		}
+
+		field := &d.fields[d.h2f[i]]
+		if withinAny(field.index, nils) {
+			continue
+		}
+		if column == "" && field.hasDefault {
+			column = field.defaultVal
+		}
+
+		if err := d.setField(field, fieldByIndexAlloc(record, field.index), column); err != nil {
+			return d.cellError(i, field, column, err)
+		}
+	}
+
+	for j, group := range d.groups {
+		if group != nil && !withinAny(d.fields[j].index, nils) {
+			if err := d.decodeGroup(record, &d.fields[j], group, row); err != nil {
+				return err
 			}
+		}
+	}
+
+	return nil
+}
 
-			value.Elem().Set(reflect.Append(value.Elem(), record.Elem()))
+// decodeGroup decodes the numbered columns of a field with a column pattern into the items of its slice or array.
+// Trailing empty cells are not included in slices, as they are only padding for a longer slice in another row.
+// Items for columns missing from a ragged row are left as zero values
+func (d *Decoder) decodeGroup(record reflect.Value, field *field, group []int, row []string) error {
+	cells := make([]string, len(group))
+	for k, i := range group {
+		if i < len(row) {
+			cells[k] = row[i]
+		}
+	}
+
+	items := fieldByIndexAlloc(record, field.index)
+	if field.typ.Kind() == reflect.Slice {
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		if len(cells) > 0 {
+			items.Set(reflect.MakeSlice(field.typ, len(cells), len(cells)))
 		}
-	default:
-		return fmt.Errorf("Decode: could not decode into type %v", ty)
 	}
 
+	for k, cell := range cells {
+		if group[k] >= len(row) {
+			continue
+		}
+		if err := d.setField(field, items.Index(k), cell); err != nil {
+			return d.cellError(group[k], field, cell, err)
+		}
+	}
 	return nil
 }
 
+// cellError wraps an error decoding the cell at index i of the current record into field
+func (d *Decoder) cellError(i int, field *field, value string, err error) error {
+	line, _ := d.reader.FieldPos(i)
+	return &DecodeError{
+		Line:   line,
+		Column: i + 1,
+		Header: d.header[i],
+		Field:  field.goName,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+// recoverable reports whether err only affects a single row, so decoding can carry on with the next one
+func recoverable(err error) bool {
+	var decodeErr *DecodeError
+	var countErr *FieldCountError
+	var parseErr *rawcsv.ParseError
+	return errors.As(err, &decodeErr) || errors.As(err, &countErr) || errors.As(err, &parseErr)
+}
+
 func validUnmarshalType(ty reflect.Type) bool {
+	if ty.Kind() == reflect.Ptr {
+		return validUnmarshalType(ty.Elem())
+	}
+
+	if reflect.PtrTo(ty).Implements(unmarshalCSV) {
+		return true
+	}
+
+	if isSQLNull(ty) {
+		return validUnmarshalType(ty.Field(0).Type)
+	}
+
+	if reflect.PtrTo(ty).Implements(sqlScanner) {
+		return true
+	}
+
+	if ty.PkgPath() == "time" && (ty.Name() == "Time" || ty.Name() == "Duration") {
+		return true
+	}
+
+	if reflect.PtrTo(ty).Implements(textUnmarshaler) {
+		return true
+	}
+
+	if isBytes(ty) {
+		return true
+	}
+
+	if ty.Kind() == reflect.Slice {
+		return (ty.Elem().Kind() != reflect.Slice || isBytes(ty.Elem())) && validUnmarshalType(ty.Elem())
+	}
+
 	switch ty.Kind() {
 	case
 		reflect.Bool,
@@ -153,21 +598,128 @@ func validUnmarshalType(ty reflect.Type) bool {
 		return true
 	}
 
-	if ty.PkgPath() == "time" && ty.Name() == "Time" {
-		return true
+	return false
+}
+
+// isNull reports whether value is one of the null tokens
+func (d *Decoder) isNull(value string) bool {
+	for _, token := range d.nullTokens {
+		if value == token {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Decoder) setField(f *field, field reflect.Value, value string) error {
+	ty := field.Type()
+
+	if d.isNull(value) || (value == "" && (isNullable(ty) || f.zeroEmpty || d.zero)) {
+		field.Set(reflect.Zero(ty))
+		return nil
+	}
+
+	if ty.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(ty.Elem()))
+		}
+		return d.setField(f, field.Elem(), value)
 	}
 
 	if reflect.PtrTo(ty).Implements(unmarshalCSV) {
-		return true
+		return field.Addr().Interface().(UnmarshalCSV).UnmarshalCSV(value)
 	}
 
-	return false
-}
+	if isSQLNull(ty) {
+		if err := d.setField(f, field.Field(0), value); err != nil {
+			return err
+		}
+		field.Field(1).SetBool(true)
+		return nil
+	}
+
+	if reflect.PtrTo(ty).Implements(sqlScanner) {
+		return field.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+
+	if ty.PkgPath() == "time" && ty.Name() == "Time" {
+		layouts := f.layouts
+		if layouts == nil {
+			layouts = d.timeLayouts
+		}
+		loc := f.location
+		if loc == nil {
+			loc = d.location
+		}
+		t, err := parseTime(value, layouts, loc)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if ty.PkgPath() == "time" && ty.Name() == "Duration" {
+		d, err := parseDuration(value, f.unit)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if reflect.PtrTo(ty).Implements(textUnmarshaler) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	if isBytes(ty) {
+		field.SetBytes([]byte(value))
+		return nil
+	}
+
+	if ty.Kind() == reflect.Slice {
+		sep := f.sep
+		if sep == "" {
+			sep = d.listSep
+		}
+		items := splitList(value, sep)
+		list := reflect.MakeSlice(ty, len(items), len(items))
+		for i, item := range items {
+			if err := d.setField(f, list.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		field.Set(list)
+		return nil
+	}
+
+	switch ty.Kind() {
+	case
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		numbers := f.numbers
+		if numbers == nil {
+			numbers = d.numbers
+		}
+		if numbers != nil {
+			value = numbers.normalize(value)
+		}
+	}
 
-func setField(field reflect.Value, value string) error {
-	switch field.Type().Kind() {
+	switch ty.Kind() {
 	case reflect.Bool:
-		b, err := strconv.ParseBool(value)
+		bools := f.bools
+		if bools == nil {
+			bools = d.bools
+		}
+		var b bool
+		var err error
+		if bools != nil {
+			b, err = bools.parse(value)
+		} else {
+			b, err = strconv.ParseBool(value)
+		}
 		if err != nil {
 			return err
 		}
@@ -262,16 +814,7 @@ func setField(field reflect.Value, value string) error {
 		return nil
 	}
 
-	if field.Type().PkgPath() == "time" && field.Type().Name() == "Time" {
-		t, err := time.Parse(time.RFC3339, value)
-		if err != nil {
-			return err
-		}
-		field.Set(reflect.ValueOf(t))
-		return nil
-	}
-
-	return field.Addr().Interface().(UnmarshalCSV).UnmarshalCSV(value)
+	return fmt.Errorf("%v is not a valid field type", ty)
 }
 
 // Unmarshal the byte slice as a csv into the value v
@@ -2,7 +2,10 @@ package csv
 
 import (
 	"bytes"
+	rawcsv "encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"testing"
@@ -79,11 +82,51 @@ hello world,2006-01-02T15:04:05-07:00,value1|1
 goodbye world,2020-07-03T16:39:44+01:00,value2|2`))
 
 	decoder := NewDecoder(data)
+	decoder.DisallowUnknownColumns()
 	var v []Data
 	err := decoder.Decode(&v)
 	assert.EqualError(t, err, "Decode: field for header[Foobar] was not found")
 }
 
+func TestDecodePassUnknownColumns(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,Extra,bar
+hello world,ignored,9223372036854775807
+goodbye world,ignored,-9223372036854775808`))
+
+	decoder := NewDecoder(data)
+	var v []Data
+	err := decoder.Decode(&v)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	expected := []Data{
+		{
+			Foo: "hello world",
+			Bar: 9223372036854775807,
+		},
+		{
+			Foo: "goodbye world",
+			Bar: -9223372036854775808,
+		},
+	}
+
+	assert.Equal(t, expected, v)
+}
+
+func TestDecodeAllowUnknownColumns(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,Extra
+hello world,ignored`))
+
+	decoder := NewDecoder(data)
+	decoder.DisallowUnknownColumns()
+	decoder.AllowUnknownColumns()
+	var v []Data
+	err := decoder.Decode(&v)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Foo: "hello world"}}, v)
+}
+
 func TestDecodeFailDecode(t *testing.T) {
 	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
 hello world,9223372036854775808,2006-01-02T15:04:05-07:00,value1|1
@@ -92,7 +135,7 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
 	decoder := NewDecoder(data)
 	var v []Data
 	err := decoder.Decode(&v)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"9223372036854775808\": value out of range")
+	assert.EqualError(t, err, "Decode: line 2, column 2: could not decode header[bar] into field Bar: strconv.ParseInt: parsing \"9223372036854775808\": value out of range")
 }
 
 func TestDecodeFailDecodeCustom(t *testing.T) {
@@ -103,7 +146,7 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
 	decoder := NewDecoder(data)
 	var v []Data
 	err := decoder.Decode(&v)
-	assert.EqualError(t, err, "invalid data for custom decode")
+	assert.EqualError(t, err, "Decode: line 2, column 4: could not decode header[Custom] into field Custom: invalid data for custom decode")
 }
 
 func TestDecodeFailInconsistentRow(t *testing.T) {
@@ -114,7 +157,8 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
 	decoder := NewDecoder(data)
 	var v []Data
 	err := decoder.Decode(&v)
-	assert.EqualError(t, err, "record on line 2: wrong number of fields")
+	assert.EqualError(t, err, "Decode: record on line 2: expected 4 fields, got 3")
+	assert.True(t, errors.Is(err, rawcsv.ErrFieldCount))
 }
 
 func TestDecodeFailInconsistentRow2(t *testing.T) {
@@ -125,7 +169,8 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
 	decoder := NewDecoder(data)
 	var v []Data
 	err := decoder.Decode(&v)
-	assert.EqualError(t, err, "record on line 2: wrong number of fields")
+	assert.EqualError(t, err, "Decode: record on line 2: expected 4 fields, got 5")
+	assert.True(t, errors.Is(err, rawcsv.ErrFieldCount))
 }
 
 func TestDecodePass_DifferentOrder(t *testing.T) {
@@ -199,3 +244,278 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
 	err := decoder.Decode(&v)
 	assert.EqualError(t, err, "Decode: could not decode into type []string - expected a slice of structs")
 }
+
+func TestDecodeRecord(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
+
+	decoder := NewDecoder(data)
+
+	time1, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05-07:00")
+	time2, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2020-07-03T16:39:44+01:00")
+
+	var v Data
+	err := decoder.DecodeRecord(&v)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, Data{
+		Foo:  "hello world",
+		Bar:  9223372036854775807,
+		Time: time1,
+		Custom: Custom{
+			A: "value1",
+			B: 1,
+		},
+	}, v)
+
+	err = decoder.DecodeRecord(&v)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, Data{
+		Foo:  "goodbye world",
+		Bar:  -9223372036854775808,
+		Time: time2,
+		Custom: Custom{
+			A: "value2",
+			B: 2,
+		},
+	}, v)
+
+	assert.Equal(t, io.EOF, decoder.DecodeRecord(&v))
+	assert.Equal(t, io.EOF, decoder.DecodeRecord(&v))
+}
+
+func TestDecodeRecordEmpty(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader(nil))
+	var v Data
+	assert.Equal(t, io.EOF, decoder.DecodeRecord(&v))
+}
+
+func TestDecodeRecordFailNotStructPointer(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1`))
+
+	decoder := NewDecoder(data)
+
+	var v Data
+	err := decoder.DecodeRecord(v)
+	assert.EqualError(t, err, "DecodeRecord: could not decode into type csv.Data - must be a pointer to a struct")
+
+	var s []Data
+	err = decoder.DecodeRecord(&s)
+	assert.EqualError(t, err, "DecodeRecord: could not decode into type *[]csv.Data - must be a pointer to a struct")
+
+	var p *Data
+	err = decoder.DecodeRecord(p)
+	assert.EqualError(t, err, "DecodeRecord: could not decode into nil *csv.Data")
+}
+
+func TestDecodeError(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+"hello
+world",9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+goodbye world,abc,2020-07-03T16:39:44+01:00,value2|2`))
+
+	decoder := NewDecoder(data)
+	var v []Data
+	err := decoder.Decode(&v)
+
+	var decodeErr *DecodeError
+	if !assert.True(t, errors.As(err, &decodeErr)) {
+		return
+	}
+
+	assert.Equal(t, 4, decodeErr.Line)
+	assert.Equal(t, 2, decodeErr.Column)
+	assert.Equal(t, "bar", decodeErr.Header)
+	assert.Equal(t, "Bar", decodeErr.Field)
+	assert.Equal(t, "abc", decodeErr.Value)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	assert.EqualError(t, err, "Decode: line 4, column 2: could not decode header[bar] into field Bar: strconv.ParseInt: parsing \"abc\": invalid syntax")
+}
+
+func TestDecodeLenient(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+bad int,abc,2006-01-02T15:04:05-07:00,value1|1
+bad custom,1,2006-01-02T15:04:05-07:00,value1
+too short,1,2006-01-02T15:04:05-07:00
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
+
+	decoder := NewDecoder(data)
+	decoder.Lenient()
+	var v []Data
+	err := decoder.Decode(&v)
+
+	var rowErrs RowErrors
+	if !assert.True(t, errors.As(err, &rowErrs)) {
+		return
+	}
+	if !assert.Len(t, rowErrs, 3) {
+		return
+	}
+	assert.EqualError(t, rowErrs[0], "Decode: line 3, column 2: could not decode header[bar] into field Bar: strconv.ParseInt: parsing \"abc\": invalid syntax")
+	assert.EqualError(t, rowErrs[1], "Decode: line 4, column 4: could not decode header[Custom] into field Custom: invalid data for custom decode")
+	assert.EqualError(t, rowErrs[2], "Decode: record on line 5: expected 4 fields, got 3")
+
+	// the row errors can be matched without multi-error unwrapping, which needs Go 1.20
+	var decodeErr *DecodeError
+	if assert.True(t, rowErrs.As(&decodeErr)) {
+		assert.Equal(t, 3, decodeErr.Line)
+	}
+	var countErr *FieldCountError
+	assert.True(t, rowErrs.As(&countErr))
+	assert.True(t, rowErrs.Is(strconv.ErrSyntax))
+	assert.True(t, rowErrs.Is(rawcsv.ErrFieldCount))
+	assert.False(t, rowErrs.Is(io.EOF))
+
+	if assert.Len(t, v, 2) {
+		assert.Equal(t, "hello world", v[0].Foo)
+		assert.Equal(t, "goodbye world", v[1].Foo)
+	}
+}
+
+func TestDecodeDisableLenient(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+bad int,abc,2006-01-02T15:04:05-07:00,value1|1
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`))
+
+	decoder := NewDecoder(data)
+	decoder.Lenient()
+	decoder.DisableLenient()
+	var v []Data
+	err := decoder.Decode(&v)
+	assert.EqualError(t, err, "Decode: line 3, column 2: could not decode header[bar] into field Bar: strconv.ParseInt: parsing \"abc\": invalid syntax")
+}
+
+func TestDecodeRaggedRows(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2
+short`))
+
+	decoder := NewDecoder(data)
+	decoder.AllowRaggedRows()
+	var v []Data
+	err := decoder.Decode(&v)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	time2, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2020-07-03T16:39:44+01:00")
+
+	expected := []Data{
+		{
+			Foo: "hello world",
+			Bar: 9223372036854775807,
+		},
+		{
+			Foo:  "goodbye world",
+			Bar:  -9223372036854775808,
+			Time: time2,
+			Custom: Custom{
+				A: "value2",
+				B: 2,
+			},
+		},
+		{
+			Foo: "short",
+		},
+	}
+
+	assert.Equal(t, expected, v)
+}
+
+func TestDecodeRaggedRowsFailTooLong(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1,extra`))
+
+	decoder := NewDecoder(data)
+	decoder.AllowRaggedRows()
+	var v []Data
+	err := decoder.Decode(&v)
+
+	var countErr *FieldCountError
+	if assert.True(t, errors.As(err, &countErr)) {
+		assert.Equal(t, &FieldCountError{Line: 2, Expected: 4, Got: 5}, countErr)
+	}
+}
+
+func TestDecodeDisallowRaggedRows(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar,Time,Custom
+hello world,9223372036854775807`))
+
+	decoder := NewDecoder(data)
+	decoder.AllowRaggedRows()
+	decoder.DisallowRaggedRows()
+	var v []Data
+	err := decoder.Decode(&v)
+	assert.EqualError(t, err, "Decode: record on line 2: expected 4 fields, got 2")
+}
+
+func TestDecodeRecordRaggedResetsFields(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,bar
+hello world,1
+goodbye world`))
+
+	decoder := NewDecoder(data)
+	decoder.AllowRaggedRows()
+
+	var v Data
+	assert.Nil(t, decoder.DecodeRecord(&v))
+	assert.Equal(t, Data{Foo: "hello world", Bar: 1}, v)
+	assert.Nil(t, decoder.DecodeRecord(&v))
+	assert.Equal(t, Data{Foo: "goodbye world"}, v)
+}
+
+func TestDecodeRequiredColumns(t *testing.T) {
+	type Required struct {
+		A string `csv:"a,required"`
+		B int    `csv:"b,required"`
+		C string `csv:"c"`
+		D int    `csv:"d,required"`
+	}
+
+	data := bytes.NewReader([]byte(`b,c
+1,hello`))
+
+	decoder := NewDecoder(data)
+	var v []Required
+	err := decoder.Decode(&v)
+
+	var missingErr *MissingColumnsError
+	if assert.True(t, errors.As(err, &missingErr)) {
+		assert.Equal(t, []string{"a", "d"}, missingErr.Columns)
+	}
+	assert.EqualError(t, err, "Decode: missing required columns: a, d")
+	assert.Empty(t, v)
+}
+
+func TestDecodeRequireAllColumns(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo,Time
+hello world,2006-01-02T15:04:05-07:00`))
+
+	decoder := NewDecoder(data)
+	decoder.RequireAllColumns()
+	var v []Data
+	err := decoder.Decode(&v)
+	assert.EqualError(t, err, "Decode: missing required columns: bar, Custom")
+	assert.Empty(t, v)
+}
+
+func TestDecodeAllowMissingColumns(t *testing.T) {
+	data := bytes.NewReader([]byte(`Foo
+hello world`))
+
+	decoder := NewDecoder(data)
+	decoder.RequireAllColumns()
+	decoder.AllowMissingColumns()
+	var v []Data
+	err := decoder.Decode(&v)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Foo: "hello world"}}, v)
+}
@@ -2,30 +2,58 @@ package csv
 
 import (
 	"bytes"
+	"database/sql/driver"
+	"encoding"
 	rawcsv "encoding/csv"
 	"fmt"
 	"io"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// MarshalCSV describes how CSV should handle types that aren't strings or built in
+// MarshalCSV describes how CSV should handle types that aren't strings or built in.
+// Types that implement driver.Valuer or encoding.TextMarshaler are also supported, but MarshalCSV takes priority
 type MarshalCSV interface {
 	MarshalCSV() string
 }
 
 var marshalCSV = reflect.TypeOf((*MarshalCSV)(nil)).Elem()
 
+// MarshalCSVWithError is like MarshalCSV, but lets the type report that it could not be encoded.
+// Any error returned is wrapped in an EncodeError
+type MarshalCSVWithError interface {
+	MarshalCSV() (string, error)
+}
+
+var marshalCSVWithError = reflect.TypeOf((*MarshalCSVWithError)(nil)).Elem()
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
 // Encoder encodes and writes the contents of a slice into a csv file
 type Encoder struct {
 	writer *rawcsv.Writer
+
+	timeLayout string         // layout for time.Time fields without a format tag
+	location   *time.Location // time zone for time.Time fields without a loc tag
+	floatFmt   byte           // format for float fields, 0 for the default
+	floatPrec  int            // precision for float fields
+	numbers    *numberFormat  // locale for number fields without a decimal or group tag
+	bools      *boolTokens    // words for bool fields without a bool tag
+	nullToken  string         // written for nil values
+	prefixSep  string         // separator between the prefix and name of inlined columns
+	listSep    string         // separator between the items of slice fields without a sep tag
 }
 
 // NewEncoder creates a new encoder from the given writer
 func NewEncoder(w io.Writer) *Encoder {
 	writer := rawcsv.NewWriter(w)
-	return &Encoder{writer}
+	return &Encoder{
+		writer:     writer,
+		timeLayout: time.RFC3339,
+		prefixSep:  ".",
+		listSep:    "|",
+	}
 }
 
 // SetDelimiter character for the csv reader
@@ -43,8 +71,73 @@ func (e *Encoder) NoCRLF() {
 	e.writer.UseCRLF = false
 }
 
+// SetTimeFormat sets the layout used to format time.Time fields, see time.Time.Format.
+// The default is time.RFC3339. A field can override this with the "format" tag option,
+// in which case the first layout of the option is used.
+// Tag options can't contain commas, so layouts like "Jan 2, 2006" have to be set here instead,
+// though the tag option does accept the names of the time package's layouts, such as RFC1123
+func (e *Encoder) SetTimeFormat(layout string) {
+	e.timeLayout = layout
+}
+
+// SetLocation sets the time zone that time.Time values are converted into before they are formatted.
+// By default times are formatted in their own zone. A field can override this with the "loc" tag option
+func (e *Encoder) SetLocation(loc *time.Location) {
+	e.location = loc
+}
+
+// SetFloatFormat sets the format and precision used to write float fields, see strconv.FormatFloat.
+// SetFloatFormat('g', -1) writes the shortest representation that decodes back to the same value.
+// The default is 'f' with a precision of 6 for float32 and 15 for float64.
+// Encode fails if the format is not one of 'b', 'e', 'E', 'f', 'g', 'G', 'x' or 'X', or the precision is below -1.
+// A field can override these with the "fmt" and "prec" tag options, e.g. `csv:"price,prec=2"`
+func (e *Encoder) SetFloatFormat(format byte, prec int) {
+	e.floatFmt = format
+	e.floatPrec = prec
+}
+
+// SetNumberFormat makes number fields use the given decimal and grouping (thousands) separators,
+// e.g. SetNumberFormat(',', '.') writes 1234.56 as "1.234,56". A grouping of 0 means numbers are not grouped.
+// Encode fails if the decimal and grouping separators are the same.
+// A field can override this with the "decimal" and "group" tag options, e.g. `csv:"amount,decimal=comma,group=dot"`
+func (e *Encoder) SetNumberFormat(decimal, grouping rune) {
+	e.numbers = newNumberFormat(decimal, grouping)
+}
+
+// SetBoolTokens sets the words that bool fields are written as. Only the first token of each is used,
+// so the same tokens can be shared with Decoder.SetBoolTokens. The default is "true" and "false".
+// A field can override this with the "bool" tag option, e.g. `csv:"active,bool=Y|N"`
+func (e *Encoder) SetBoolTokens(trueTokens, falseTokens []string) {
+	e.bools = &boolTokens{trueTokens: trueTokens, falseTokens: falseTokens}
+}
+
+// SetNullToken sets the cell value written for nil pointers and null driver.Valuer values, such as "NULL" or `\N`.
+// The default is an empty cell
+func (e *Encoder) SetNullToken(token string) {
+	e.nullToken = token
+}
+
+// SetPrefixSeparator sets the separator between the name of a field with the "inline" option
+// and the names of its nested fields. The default is ".", so `csv:"billing,inline"` gives columns like "billing.street"
+func (e *Encoder) SetPrefixSeparator(sep string) {
+	e.prefixSep = sep
+}
+
+// SetListSeparator sets the separator between the items of slice fields, which are encoded into a single cell.
+// Separators and backslashes within an item are escaped with a backslash. The default is "|".
+// Encoding fails if the separator is empty or contains a backslash.
+// Nil, empty and single empty string slices are all written as an empty cell, which decodes as a nil slice.
+// A field can override this with the "sep" tag option, e.g. `csv:"tags,sep=;"`
+func (e *Encoder) SetListSeparator(sep string) {
+	e.listSep = sep
+}
+
 // Encode and write the value of v into a csv
 func (e *Encoder) Encode(v interface{}) error {
+	if err := e.checkOptions(); err != nil {
+		return err
+	}
+
 	value := reflect.ValueOf(v)
 	ty := value.Type()
 
@@ -60,32 +153,85 @@ func (e *Encoder) Encode(v interface{}) error {
 			return fmt.Errorf("Encode: could not encode type %v - expected a collection of structs", ty)
 		}
 
-		fl := elem.NumField()
-		fields := make([]string, 0, fl)
-		for i := 0; i < fl; i++ {
-			field := elem.Field(i)
+		fields, err := typeFields(elem, e.prefixSep)
+		if err != nil {
+			return fmt.Errorf("Encode: %w", err)
+		}
 
-			column := field.Tag.Get("csv")
-			if column == "" {
-				column = field.Name
+		// fields with a column pattern are written as numbered columns,
+		// as many as the longest slice in the collection. At least one is always written,
+		// so the header still names the field when every slice is empty
+		l := value.Len()
+		widths := make([]int, len(fields))
+		for j, field := range fields {
+			switch {
+			case !field.pattern:
+				widths[j] = 1
+			case field.typ.Kind() == reflect.Array:
+				widths[j] = field.typ.Len()
+			default:
+				widths[j] = 1
+				for i := 0; i < l; i++ {
+					if fv, ok := fieldByIndex(value.Index(i), field.index); ok && fv.Len() > widths[j] {
+						widths[j] = fv.Len()
+					}
+				}
 			}
+		}
 
-			if !validMarshalType(field.Type) {
-				return fmt.Errorf("Encode: %v is not a valid field type - try implement MarshalCSV for it", field.Type)
+		header := make([]string, 0, len(fields))
+		for j, field := range fields {
+			if !validMarshalType(field.itemType()) {
+				return fmt.Errorf("Encode: %v is not a valid field type - try implement MarshalCSV for it", field.itemType())
 			}
 
-			fields = append(fields, column)
+			if !field.pattern {
+				header = append(header, field.name)
+				continue
+			}
+			for n := 1; n <= widths[j]; n++ {
+				header = append(header, patternColumn(field.name, n))
+			}
 		}
 
-		if err := e.writer.Write(fields); err != nil {
+		if err := e.writer.Write(header); err != nil {
 			return err
 		}
 
-		l := value.Len()
 		for i := 0; i < l; i++ {
-			row := make([]string, fl)
-			for j := 0; j < fl; j++ {
-				row[j] = getValue(value.Index(i).Field(j))
+			row := make([]string, 0, len(header))
+			for j, field := range fields {
+				fv, ok := fieldByIndex(value.Index(i), field.index)
+				if field.pattern {
+					for k := 0; k < widths[j]; k++ {
+						var cell string
+						switch {
+						case !ok:
+							cell = e.nullToken
+						case k < fv.Len():
+							var err error
+							if cell, err = e.getValue(&field, fv.Index(k)); err != nil {
+								return e.cellError(i, len(row), header, &field, err)
+							}
+						}
+						row = append(row, cell)
+					}
+					continue
+				}
+
+				if !ok {
+					row = append(row, e.nullToken)
+					continue
+				}
+				if field.omitEmpty && fv.IsZero() {
+					row = append(row, "")
+					continue
+				}
+				cell, err := e.getValue(&field, fv)
+				if err != nil {
+					return e.cellError(i, len(row), header, &field, err)
+				}
+				row = append(row, cell)
 			}
 			if err := e.writer.Write(row); err != nil {
 				return err
@@ -99,6 +245,36 @@ func (e *Encoder) Encode(v interface{}) error {
 	return e.writer.Error()
 }
 
+// checkOptions reports an error for any invalid settings, like encoding/csv does for an invalid Comma
+func (e *Encoder) checkOptions() error {
+	if err := validListSeparator(e.listSep); err != nil {
+		return fmt.Errorf("Encode: %w", err)
+	}
+	if e.floatFmt != 0 && !strings.ContainsRune(floatFormats, rune(e.floatFmt)) {
+		return fmt.Errorf("Encode: unknown float format %q", string(e.floatFmt))
+	}
+	if e.floatPrec < -1 {
+		return fmt.Errorf("Encode: invalid float precision %d", e.floatPrec)
+	}
+	if e.numbers != nil {
+		if err := e.numbers.validate(); err != nil {
+			return fmt.Errorf("Encode: %w", err)
+		}
+	}
+	return nil
+}
+
+// cellError wraps an error encoding the cell at index j of row i from field
+func (e *Encoder) cellError(i, j int, header []string, field *field, err error) error {
+	return &EncodeError{
+		Row:    i,
+		Column: j + 1,
+		Header: header[j],
+		Field:  field.goName,
+		Err:    err,
+	}
+}
+
 // Marshal the value v into a csv
 func Marshal(v interface{}) ([]byte, error) {
 	b := bytes.NewBuffer([]byte{})
@@ -108,6 +284,34 @@ func Marshal(v interface{}) ([]byte, error) {
 }
 
 func validMarshalType(ty reflect.Type) bool {
+	if ty.Kind() == reflect.Ptr {
+		return validMarshalType(ty.Elem())
+	}
+
+	if reflect.PtrTo(ty).Implements(marshalCSV) || reflect.PtrTo(ty).Implements(marshalCSVWithError) {
+		return true
+	}
+
+	if reflect.PtrTo(ty).Implements(driverValuer) {
+		return true
+	}
+
+	if ty.PkgPath() == "time" && (ty.Name() == "Time" || ty.Name() == "Duration") {
+		return true
+	}
+
+	if reflect.PtrTo(ty).Implements(textMarshaler) {
+		return true
+	}
+
+	if isBytes(ty) {
+		return true
+	}
+
+	if ty.Kind() == reflect.Slice {
+		return (ty.Elem().Kind() != reflect.Slice || isBytes(ty.Elem())) && validMarshalType(ty.Elem())
+	}
+
 	switch ty.Kind() {
 	case
 		reflect.Bool,
@@ -127,36 +331,154 @@ func validMarshalType(ty reflect.Type) bool {
 		return true
 	}
 
-	if ty.PkgPath() == "time" && ty.Name() == "Time" {
-		return true
+	return false
+}
+
+// asInterface returns field as a value of the interface type iface.
+// If only the pointer type implements iface, the address of field is used instead
+func asInterface(field reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if field.Type().Implements(iface) {
+		return field.Interface(), true
 	}
 
-	if reflect.PtrTo(ty).Implements(marshalCSV) {
-		return true
+	if reflect.PtrTo(field.Type()).Implements(iface) {
+		if !field.CanAddr() {
+			ptr := reflect.New(field.Type())
+			ptr.Elem().Set(field)
+			return ptr.Interface(), true
+		}
+		return field.Addr().Interface(), true
 	}
 
-	return false
+	return nil, false
 }
 
-func getValue(field reflect.Value) string {
-	switch field.Type().Kind() {
+func (e *Encoder) getValue(f *field, field reflect.Value) (string, error) {
+	ty := field.Type()
+
+	if ty.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return e.nullToken, nil
+		}
+		return e.getValue(f, field.Elem())
+	}
+
+	if m, ok := asInterface(field, marshalCSV); ok {
+		return m.(MarshalCSV).MarshalCSV(), nil
+	}
+
+	if m, ok := asInterface(field, marshalCSVWithError); ok {
+		return m.(MarshalCSVWithError).MarshalCSV()
+	}
+
+	if m, ok := asInterface(field, driverValuer); ok {
+		v, err := m.(driver.Valuer).Value()
+		if err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case nil:
+			return e.nullToken, nil
+		case []byte:
+			return string(v), nil
+		}
+		return e.getValue(f, reflect.ValueOf(v))
+	}
+
+	if ty.PkgPath() == "time" && ty.Name() == "Time" {
+		layout := e.timeLayout
+		if len(f.layouts) > 0 {
+			layout = f.layouts[0]
+		}
+		loc := e.location
+		if f.location != nil {
+			loc = f.location
+		}
+		return formatTime(field.Interface().(time.Time), layout, loc), nil
+	}
+
+	if ty.PkgPath() == "time" && ty.Name() == "Duration" {
+		return formatDuration(time.Duration(field.Int()), f.unit), nil
+	}
+
+	if m, ok := asInterface(field, textMarshaler); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch ty.Kind() {
+	case reflect.Slice:
+		if isBytes(ty) {
+			return string(field.Bytes()), nil
+		}
+		items := make([]string, field.Len())
+		for i := range items {
+			item, err := e.getValue(f, field.Index(i))
+			if err != nil {
+				return "", fmt.Errorf("item %d: %w", i, err)
+			}
+			items[i] = item
+		}
+		sep := f.sep
+		if sep == "" {
+			sep = e.listSep
+		}
+		return joinList(items, sep), nil
 	case reflect.Bool:
-		return fmt.Sprint(field.Bool())
+		bools := f.bools
+		if bools == nil {
+			bools = e.bools
+		}
+		if bools != nil {
+			return bools.format(field.Bool()), nil
+		}
+		return fmt.Sprint(field.Bool()), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.FormatInt(field.Int(), 10)
+		return e.localize(f, strconv.FormatInt(field.Int(), 10)), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(field.Uint(), 10)
+		return e.localize(f, strconv.FormatUint(field.Uint(), 10)), nil
 	case reflect.Float32:
-		return strconv.FormatFloat(field.Float(), 'f', 6, 32)
+		format, prec := e.floatFormat(f, 6)
+		return e.localize(f, strconv.FormatFloat(field.Float(), format, prec, 32)), nil
 	case reflect.Float64:
-		return strconv.FormatFloat(field.Float(), 'f', 15, 64)
+		format, prec := e.floatFormat(f, 15)
+		return e.localize(f, strconv.FormatFloat(field.Float(), format, prec, 64)), nil
 	case reflect.String:
-		return field.String()
+		return field.String(), nil
+	}
+
+	return "", fmt.Errorf("%v is not a valid field type", ty)
+}
+
+// floatFormat returns the format and precision to write a float field with.
+// defaultPrec is used when neither the encoder nor the field set a format.
+// A field with a precision but no format is written with 'f', so prec=2 always means 2 decimal places
+func (e *Encoder) floatFormat(f *field, defaultPrec int) (byte, int) {
+	format, prec := e.floatFmt, e.floatPrec
+	if format == 0 {
+		format, prec = 'f', defaultPrec
 	}
 
-	if field.Type().PkgPath() == "time" && field.Type().Name() == "Time" {
-		return field.Interface().(time.Time).Format(time.RFC3339)
+	switch {
+	case f.floatFmt != 0 && f.hasPrec:
+		format, prec = f.floatFmt, f.floatPrec
+	case f.floatFmt != 0:
+		format, prec = f.floatFmt, -1
+	case f.hasPrec:
+		format, prec = 'f', f.floatPrec
 	}
 
-	return field.Interface().(MarshalCSV).MarshalCSV()
+	return format, prec
+}
+
+// localize rewrites a number to use the separators of the field's locale, if one is set
+func (e *Encoder) localize(f *field, value string) string {
+	numbers := f.numbers
+	if numbers == nil {
+		numbers = e.numbers
+	}
+	if numbers == nil {
+		return value
+	}
+	return numbers.localize(value)
 }
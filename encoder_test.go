@@ -181,3 +181,14 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2
 	assert.Nil(t, err)
 	assert.Equal(t, expected, string(bytes))
 }
+
+func TestEncodeTagOptions(t *testing.T) {
+	type Required struct {
+		A string `csv:"a,required"`
+		B int    `csv:",required"`
+	}
+
+	b, err := Marshal([]Required{{A: "hello", B: 1}})
+	assert.Nil(t, err)
+	assert.Equal(t, "a,B\nhello,1\n", string(b))
+}
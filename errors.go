@@ -0,0 +1,110 @@
+package csv
+
+import (
+	rawcsv "encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DecodeError describes a csv cell that could not be decoded into its struct field
+type DecodeError struct {
+	Line   int    // Line of the input the cell is on, starting at 1
+	Column int    // Column index of the cell within its record, starting at 1
+	Header string // Header name of the column
+	Field  string // Field is the name of the struct field being decoded into
+	Value  string // Value is the raw contents of the cell
+	Err    error  // Err is the underlying cause
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Decode: line %d, column %d: could not decode header[%s] into field %s: %v", e.Line, e.Column, e.Header, e.Field, e.Err)
+}
+
+// Unwrap returns the underlying cause of the error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError describes a struct field that could not be encoded into a csv cell
+type EncodeError struct {
+	Row    int    // Row is the index of the value being encoded within its collection
+	Column int    // Column index of the cell within its record, starting at 1
+	Header string // Header name of the column
+	Field  string // Field is the name of the struct field being encoded
+	Err    error  // Err is the underlying cause
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("Encode: row %d, column %d: could not encode field %s into header[%s]: %v", e.Row, e.Column, e.Field, e.Header, e.Err)
+}
+
+// Unwrap returns the underlying cause of the error
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// FieldCountError describes a record that has a different number of cells to the header row.
+// It wraps encoding/csv.ErrFieldCount, so it can be detected using errors.Is
+type FieldCountError struct {
+	Line     int // Line of the input the record starts on
+	Expected int // Expected number of cells, taken from the header row
+	Got      int // Got is the number of cells in the record
+}
+
+func (e *FieldCountError) Error() string {
+	return fmt.Sprintf("Decode: record on line %d: expected %d fields, got %d", e.Line, e.Expected, e.Got)
+}
+
+// Unwrap returns encoding/csv.ErrFieldCount
+func (e *FieldCountError) Unwrap() error {
+	return rawcsv.ErrFieldCount
+}
+
+// MissingColumnsError lists every required column that was not found in the header row
+type MissingColumnsError struct {
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("Decode: missing required columns: %s", strings.Join(e.Columns, ", "))
+}
+
+// RowErrors is returned by a lenient Decoder, listing the error of every row that could not be decoded.
+// Each error carries the line number it occurred on
+type RowErrors []error
+
+func (e RowErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("Decode: %d rows could not be decoded: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of every row that could not be decoded
+func (e RowErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether the error of any row matches target.
+// Versions of Go before 1.20 don't look through Unwrap() []error, so errors.Is relies on this
+func (e RowErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first row error that matches target, see errors.As.
+// Versions of Go before 1.20 don't look through Unwrap() []error, so errors.As relies on this
+func (e RowErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package csv_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -111,6 +112,28 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`)
 	// ]
 }
 
+func ExampleDecoder_DecodeRecord() {
+	data := strings.NewReader(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`)
+
+	decoder := csv.NewDecoder(data)
+	for {
+		var record Data
+		err := decoder.DecodeRecord(&record)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(record.Foo, record.Bar)
+	}
+	// Output:
+	// hello world 9223372036854775807
+	// goodbye world -9223372036854775808
+}
+
 func ExampleDecoder_SetDelimiter() {
 	data := strings.NewReader(`Foo~bar~Time~Custom
 hello world~9223372036854775807~2006-01-02T15:04:05-07:00~value1|1
@@ -264,6 +287,40 @@ goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`)
 	// ]
 }
 
+func ExampleDecoder_DisallowUnknownColumns() {
+	data := strings.NewReader(`Foo,bar,Time,Custom,Extra
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1,unknown`)
+
+	decoder := csv.NewDecoder(data)
+	decoder.DisallowUnknownColumns()
+	output := []Data{}
+	err := decoder.Decode(&output)
+	fmt.Println(err)
+	// Output:
+	// Decode: field for header[Extra] was not found
+}
+
+func ExampleDecoder_Lenient() {
+	data := strings.NewReader(`Foo,bar,Time,Custom
+hello world,9223372036854775807,2006-01-02T15:04:05-07:00,value1|1
+broken,not a number,2006-01-02T15:04:05-07:00,value1|1
+goodbye world,-9223372036854775808,2020-07-03T16:39:44+01:00,value2|2`)
+
+	decoder := csv.NewDecoder(data)
+	decoder.Lenient()
+	output := []Data{}
+	err := decoder.Decode(&output)
+
+	for _, record := range output {
+		fmt.Println(record.Foo)
+	}
+	fmt.Println(err)
+	// Output:
+	// hello world
+	// goodbye world
+	// Decode: 1 rows could not be decoded: Decode: line 3, column 2: could not decode header[bar] into field Bar: strconv.ParseInt: parsing "not a number": invalid syntax
+}
+
 func ExampleMarshal() {
 	time1 := time.Date(2006, 01, 02, 15, 04, 05, 0, time.FixedZone("MST", -7*60*60))
 	time2 := time.Date(2020, 07, 03, 16, 39, 44, 0, time.FixedZone("BST", 1*60*60))
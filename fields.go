@@ -0,0 +1,522 @@
+package csv
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes how a struct field maps onto a csv column
+type field struct {
+	name     string // name of the column
+	index    []int  // index sequence of the field, see reflect.Value.FieldByIndex
+	typ      reflect.Type
+	goName   string // name of the struct field, including the fields it is embedded in
+	tagged   bool   // whether the column name came from a tag
+	required bool
+
+	omitEmpty  bool   // encode the zero value as an empty cell
+	zeroEmpty  bool   // decode empty cells as the zero value
+	hasDefault bool   // decode empty cells as defaultValue
+	defaultVal string // value to decode when a cell is empty
+
+	layouts  []string       // time layouts, the first is used when encoding
+	location *time.Location // time zone for time fields
+	unit     time.Duration  // unit of time.Duration fields written as plain numbers
+
+	floatFmt  byte // format of float fields, see strconv.FormatFloat. 0 if not set
+	floatPrec int  // precision of float fields, only used if hasPrec is set
+	hasPrec   bool
+
+	numbers *numberFormat // locale of number fields, nil if not set
+	bools   *boolTokens   // words for bool fields, nil if not set
+
+	sep string // separator between the items of slice fields, empty if not set
+
+	pattern bool // name is a pattern of numbered columns, such as "score_*", that hold the items of a slice or array
+}
+
+// typeFields returns the csv columns for the fields of the struct type ty.
+// Unexported fields and fields tagged with "-" are skipped.
+// Untagged anonymous struct fields are flattened, unless they are encoded as a single cell (see isCellType), with their fields promoted
+// following the same rules as encoding/json: a shallower field hides deeper fields of the same name,
+// and when several fields at the same depth share a name, only a single tagged one is kept.
+// Struct fields with the "inline" option are also flattened, but with their column name and sep as a prefix
+func typeFields(ty reflect.Type, sep string) ([]field, error) {
+	type embedded struct {
+		typ     reflect.Type
+		index   []int
+		goName  string
+		prefix  string         // prefix of the column names, from inline fields
+		parents []reflect.Type // inlined types containing this one, to detect recursion
+	}
+	type visit struct {
+		typ    reflect.Type
+		prefix string
+	}
+
+	var fields []field
+	var inlines []embedded
+	next := []embedded{{typ: ty}}
+	visited := map[visit]bool{}
+
+	for len(next) > 0 {
+		current := next
+		next = nil
+
+		// types embedded multiple times at the same depth are all walked, so that their fields conflict
+		for _, e := range current {
+			visited[visit{e.typ, e.prefix}] = true
+		}
+
+		for _, e := range current {
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if sf.Anonymous {
+					// unexported embedded structs can still have exported fields promoted from them,
+					// but pointers to them can't be allocated through reflection
+					if sf.PkgPath != "" && (ft.Kind() != reflect.Struct || sf.Type.Kind() == reflect.Ptr) {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					// unexported fields can't be set or read through reflection
+					continue
+				}
+
+				tag := sf.Tag.Get("csv")
+				if tag == "-" {
+					continue
+				}
+
+				name, opts := parseTag(tag)
+				if err := opts.validate(); err != nil {
+					return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+				}
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				goName := sf.Name
+				if e.goName != "" {
+					goName = e.goName + "." + sf.Name
+				}
+
+				if opts.Contains("inline") {
+					if ft.Kind() != reflect.Struct {
+						return nil, fmt.Errorf("field %s: only structs can be inlined, not %v", goName, sf.Type)
+					}
+					if isCellType(ft) {
+						return nil, fmt.Errorf("field %s: %v is encoded as a single cell and cannot be inlined", goName, sf.Type)
+					}
+					parents := append(append([]reflect.Type{}, e.parents...), e.typ)
+					for _, parent := range parents {
+						if parent == ft {
+							return nil, fmt.Errorf("field %s: cannot inline recursive type %v", goName, sf.Type)
+						}
+					}
+
+					if name == "" {
+						name = sf.Name
+					}
+					inlined := embedded{
+						typ:     ft,
+						index:   index,
+						goName:  goName,
+						prefix:  e.prefix + name + sep,
+						parents: parents,
+					}
+					next = append(next, inlined)
+					inlines = append(inlines, inlined)
+					continue
+				}
+
+				// unexported embedded structs are always flattened, even if they marshal themselves,
+				// as only their promoted fields can be read through reflection
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && (sf.PkgPath != "" || !isCellType(ft)) {
+					if !visited[visit{ft, e.prefix}] {
+						next = append(next, embedded{
+							typ:     ft,
+							index:   index,
+							goName:  goName,
+							prefix:  e.prefix,
+							parents: e.parents,
+						})
+					}
+					continue
+				}
+				if sf.PkgPath != "" {
+					// a tagged unexported embedded struct would be a column that can't be read or set
+					continue
+				}
+
+				f, err := newField(sf, name, opts)
+				if err != nil {
+					return nil, err
+				}
+				f.name = e.prefix + f.name
+				f.index = index
+				f.goName = goName
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	fields = dominantFields(fields)
+
+	// an inlined struct without any columns would silently drop the field
+	for _, e := range inlines {
+		if !hasFieldWithin(fields, e.index) {
+			return nil, fmt.Errorf("field %s: inlined type %v has no columns", e.goName, e.typ)
+		}
+	}
+
+	return fields, nil
+}
+
+// hasFieldWithin reports whether any of fields is nested within the struct field at index
+func hasFieldWithin(fields []field, index []int) bool {
+	for _, f := range fields {
+		if within(f.index, index) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether the field at index is nested within the struct field at parent
+func within(index, parent []int) bool {
+	return len(index) > len(parent) && reflect.DeepEqual(index[:len(parent)], parent)
+}
+
+// withinAny reports whether the field at index is nested within any of the struct fields at parents
+func withinAny(index []int, parents [][]int) bool {
+	for _, parent := range parents {
+		if within(index, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// dominantFields removes the fields hidden by another field of the same name, keeping the rest in declaration order.
+// Fields are collected breadth first, so the shallowest fields of each name are found first
+func dominantFields(fields []field) []field {
+	byName := map[string][]int{}
+	for i, f := range fields {
+		byName[f.name] = append(byName[f.name], i)
+	}
+
+	keep := make([]bool, len(fields))
+	for _, indices := range byName {
+		depth := len(fields[indices[0]].index)
+
+		var shallowest, tagged []int
+		for _, i := range indices {
+			if len(fields[i].index) != depth {
+				break
+			}
+			shallowest = append(shallowest, i)
+			if fields[i].tagged {
+				tagged = append(tagged, i)
+			}
+		}
+
+		switch {
+		case len(shallowest) == 1:
+			keep[shallowest[0]] = true
+		case len(tagged) == 1:
+			keep[tagged[0]] = true
+		}
+	}
+
+	dominant := make([]field, 0, len(fields))
+	for i, f := range fields {
+		if keep[i] {
+			dominant = append(dominant, f)
+		}
+	}
+
+	sort.SliceStable(dominant, func(i, j int) bool {
+		return indexLess(dominant[i].index, dominant[j].index)
+	})
+	return dominant
+}
+
+// indexLess reports whether the field at index a is declared before the field at index b
+func indexLess(a, b []int) bool {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// isCellType reports whether ty encodes or decodes itself as a single cell,
+// through one of the marshaler or sql interfaces or as a time.Time, so a struct doesn't have its fields flattened
+func isCellType(ty reflect.Type) bool {
+	if ty.PkgPath() == "time" && ty.Name() == "Time" {
+		return true
+	}
+
+	ptr := reflect.PtrTo(ty)
+	for _, iface := range []reflect.Type{unmarshalCSV, marshalCSV, marshalCSVWithError, textUnmarshaler, textMarshaler, sqlScanner, driverValuer} {
+		if ptr.Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// floatFormats are the formats accepted by strconv.FormatFloat, for the "fmt" tag option and Encoder.SetFloatFormat
+const floatFormats = "beEfgGxX"
+
+// newField creates the column for the struct field sf from its tag name and options
+func newField(sf reflect.StructField, name string, opts tagOptions) (field, error) {
+	tagged := name != ""
+	if !tagged {
+		name = sf.Name
+	}
+
+	pattern := strings.Contains(name, "*")
+	if pattern && sf.Type.Kind() != reflect.Slice && sf.Type.Kind() != reflect.Array {
+		return field{}, fmt.Errorf("field %s: column pattern %q needs a slice or array, not %v", sf.Name, name, sf.Type)
+	}
+
+	defaultVal, hasDefault := opts.Get("default")
+	var layouts []string
+	if format, ok := opts.Get("format"); ok {
+		layouts = strings.Split(format, "|")
+		for i, layout := range layouts {
+			if named, ok := layoutNames[layout]; ok {
+				layouts[i] = named
+			}
+		}
+	}
+	var location *time.Location
+	if loc, ok := opts.Get("loc"); ok {
+		var err error
+		if location, err = time.LoadLocation(loc); err != nil {
+			return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+	var unit time.Duration
+	if u, ok := opts.Get("unit"); ok {
+		if unit, ok = durationUnits[u]; !ok {
+			return field{}, fmt.Errorf("field %s: unknown duration unit %q", sf.Name, u)
+		}
+	}
+	var floatFmt byte
+	if f, ok := opts.Get("fmt"); ok {
+		if len(f) != 1 || !strings.Contains(floatFormats, f) {
+			return field{}, fmt.Errorf("field %s: unknown float format %q", sf.Name, f)
+		}
+		floatFmt = f[0]
+	}
+	var numbers *numberFormat
+	decimal, hasDecimal := opts.Get("decimal")
+	group, hasGroup := opts.Get("group")
+	if hasDecimal || hasGroup {
+		var dec, grp rune
+		var err error
+		if hasDecimal {
+			if dec, err = parseSeparator(decimal); err != nil {
+				return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+		}
+		if hasGroup {
+			if grp, err = parseSeparator(group); err != nil {
+				return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+		}
+		numbers = newNumberFormat(dec, grp)
+		if err := numbers.validate(); err != nil {
+			return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+	var bools *boolTokens
+	if b, ok := opts.Get("bool"); ok {
+		var err error
+		if bools, err = parseBoolTag(b); err != nil {
+			return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+	sep, hasSep := opts.Get("sep")
+	if r, ok := separatorNames[sep]; ok && r != 0 {
+		sep = string(r)
+	}
+	if hasSep {
+		if err := validListSeparator(sep); err != nil {
+			return field{}, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+	var floatPrec int
+	p, hasPrec := opts.Get("prec")
+	if hasPrec {
+		var err error
+		if floatPrec, err = strconv.Atoi(p); err != nil || floatPrec < -1 {
+			return field{}, fmt.Errorf("field %s: invalid float precision %q", sf.Name, p)
+		}
+	}
+
+	return field{
+		name:       name,
+		typ:        sf.Type,
+		tagged:     tagged,
+		required:   opts.Contains("required"),
+		omitEmpty:  opts.Contains("omitempty"),
+		zeroEmpty:  opts.Contains("zeroempty"),
+		hasDefault: hasDefault,
+		defaultVal: defaultVal,
+		layouts:    layouts,
+		location:   location,
+		unit:       unit,
+		floatFmt:   floatFmt,
+		floatPrec:  floatPrec,
+		hasPrec:    hasPrec,
+		numbers:    numbers,
+		bools:      bools,
+		sep:        sep,
+		pattern:    pattern,
+	}, nil
+}
+
+// itemType returns the type of the values held in each column of f
+func (f *field) itemType() reflect.Type {
+	if f.pattern {
+		return f.typ.Elem()
+	}
+	return f.typ
+}
+
+// matchPattern reports whether header is one of the numbered columns of pattern,
+// such as "score_2" for "score_*", and returns its number
+func matchPattern(pattern, header string) (int, bool) {
+	star := strings.Index(pattern, "*")
+	prefix, suffix := pattern[:star], pattern[star+1:]
+	if len(header) <= len(prefix)+len(suffix) || !strings.HasPrefix(header, prefix) || !strings.HasSuffix(header, suffix) {
+		return 0, false
+	}
+
+	digits := header[len(prefix) : len(header)-len(suffix)]
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// patternColumn returns the name of the nth numbered column of pattern
+func patternColumn(pattern string, n int) string {
+	return strings.Replace(pattern, "*", strconv.Itoa(n), 1)
+}
+
+// fieldByIndexAlloc returns the nested field of v at index, allocating any nil embedded pointers on the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndex returns the nested field of v at index,
+// or false if it is inside an embedded pointer that is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// tagOptions is the string following a comma in a struct field's "csv" tag,
+// or the empty string. It does not include the leading comma
+type tagOptions string
+
+// parseTag splits a struct field's csv tag into its name and comma-separated options
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// Contains reports whether a comma-separated list of options contains a particular option
+func (o tagOptions) Contains(option string) bool {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == option {
+			return true
+		}
+		s = next
+	}
+	return false
+}
+
+// knownOptions are the options of a csv tag, those that take a value end with '='
+var knownOptions = []string{
+	"required", "omitempty", "zeroempty", "inline",
+	"default=", "format=", "loc=", "unit=", "fmt=", "prec=", "decimal=", "group=", "bool=", "sep=",
+}
+
+// validate reports an error for any option that isn't known.
+// Option values can't contain commas, so this also catches values that were cut short by one,
+// like the layout in `csv:"date,format=Jan 2, 2006"`
+func (o tagOptions) validate() error {
+	for _, option := range strings.Split(string(o), ",") {
+		known := option == ""
+		for _, k := range knownOptions {
+			if option == k || strings.HasSuffix(k, "=") && strings.HasPrefix(option, k) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown tag option %q, option values can't contain commas", option)
+		}
+	}
+	return nil
+}
+
+// Get returns the value of a "key=value" option, and whether the option was present
+func (o tagOptions) Get(key string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if strings.HasPrefix(s, key+"=") {
+			return s[len(key)+1:], true
+		}
+		s = next
+	}
+	return "", false
+}
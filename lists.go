@@ -0,0 +1,74 @@
+package csv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// listEscape escapes separators and itself within the items of a list
+const listEscape = '\\'
+
+// isBytes reports whether ty is a byte slice, which is written as the text it holds rather than as a list of numbers.
+// Slices of byte types that marshal themselves, see isCellType, are still lists
+func isBytes(ty reflect.Type) bool {
+	return ty.Kind() == reflect.Slice && ty.Elem().Kind() == reflect.Uint8 && !isCellType(ty.Elem())
+}
+
+// validListSeparator reports an error if sep can't be used to separate the items of a list
+func validListSeparator(sep string) error {
+	if sep == "" || strings.ContainsRune(sep, listEscape) {
+		return fmt.Errorf("invalid list separator %q", sep)
+	}
+	return nil
+}
+
+// joinList joins items into a single cell, escaping any separators or escape characters within them
+func joinList(items []string, sep string) string {
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		for len(item) > 0 {
+			if item[0] == listEscape {
+				b.WriteByte(listEscape)
+				b.WriteByte(listEscape)
+				item = item[1:]
+			} else if strings.HasPrefix(item, sep) {
+				b.WriteByte(listEscape)
+				b.WriteString(sep)
+				item = item[len(sep):]
+			} else {
+				b.WriteByte(item[0])
+				item = item[1:]
+			}
+		}
+	}
+	return b.String()
+}
+
+// splitList splits a cell written by joinList back into its items
+func splitList(value, sep string) []string {
+	var items []string
+	var b strings.Builder
+	for len(value) > 0 {
+		if value[0] == listEscape && len(value) > 1 {
+			if strings.HasPrefix(value[1:], sep) {
+				b.WriteString(sep)
+				value = value[1+len(sep):]
+			} else {
+				b.WriteByte(value[1])
+				value = value[2:]
+			}
+		} else if strings.HasPrefix(value, sep) {
+			items = append(items, b.String())
+			b.Reset()
+			value = value[len(sep):]
+		} else {
+			b.WriteByte(value[0])
+			value = value[1:]
+		}
+	}
+	return append(items, b.String())
+}
@@ -0,0 +1,113 @@
+package csv
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// numberFormat describes the separators used to write numbers in a locale
+type numberFormat struct {
+	decimal  rune // decimal separator
+	grouping rune // thousands separator, 0 for none
+}
+
+// separatorNames are the names accepted by the "decimal", "group" and "sep" tag options,
+// for separators that can't be written in a struct tag
+var separatorNames = map[string]rune{
+	"comma":      ',',
+	"dot":        '.',
+	"period":     '.',
+	"space":      ' ',
+	"apostrophe": '\'',
+	"underscore": '_',
+	"semicolon":  ';',
+	"pipe":       '|',
+	"tab":        '\t',
+	"none":       0,
+}
+
+// parseSeparator parses the value of a "decimal" or "group" tag option
+func parseSeparator(name string) (rune, error) {
+	if r, ok := separatorNames[name]; ok {
+		return r, nil
+	}
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		return r, nil
+	}
+	return 0, fmt.Errorf("unknown separator %q", name)
+}
+
+// newNumberFormat creates a number format, using '.' if decimal is 0
+func newNumberFormat(decimal, grouping rune) *numberFormat {
+	if decimal == 0 {
+		decimal = '.'
+	}
+	return &numberFormat{decimal: decimal, grouping: grouping}
+}
+
+// validate reports an error if the decimal and grouping separators can't be told apart
+func (n *numberFormat) validate() error {
+	if n.decimal == n.grouping {
+		return fmt.Errorf("decimal and grouping separators are both %q", n.decimal)
+	}
+	return nil
+}
+
+// normalize turns a localised number into one that strconv can parse.
+// Grouping separators, currency symbols and spaces are removed,
+// the decimal separator is replaced with '.' and numbers in parentheses are made negative
+func (n *numberFormat) normalize(value string) string {
+	value = strings.TrimSpace(value)
+
+	var b strings.Builder
+	if len(value) > 1 && value[0] == '(' && value[len(value)-1] == ')' {
+		b.WriteByte('-')
+		value = value[1 : len(value)-1]
+	}
+
+	for _, r := range value {
+		switch {
+		case r == n.grouping:
+		case n.grouping == ' ' && unicode.Is(unicode.Zs, r):
+			// non-breaking spaces are often used for grouping in place of a plain space
+		case unicode.Is(unicode.Sc, r), unicode.IsSpace(r):
+		case r == n.decimal:
+			b.WriteByte('.')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// localize rewrites a number formatted by strconv to use the separators of the locale
+func (n *numberFormat) localize(value string) string {
+	sign := ""
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		sign, value = value[:1], value[1:]
+	}
+
+	// digits before the decimal point or exponent are grouped, the rest is kept as it is
+	end := strings.IndexAny(value, ".eEpP")
+	if end == -1 {
+		end = len(value)
+	}
+	whole, rest := value[:end], value[end:]
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range whole {
+		if i > 0 && n.grouping != 0 && (len(whole)-i)%3 == 0 {
+			b.WriteRune(n.grouping)
+		}
+		b.WriteRune(r)
+	}
+	if strings.HasPrefix(rest, ".") {
+		b.WriteRune(n.decimal)
+		rest = rest[1:]
+	}
+	b.WriteString(rest)
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package csv
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+)
+
+var sqlScanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+var driverValuer = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isSQLNull reports whether ty is one of the database/sql Null types, such as sql.NullInt64.
+// These are structs of a value followed by a Valid flag, so the value can be decoded like any other field
+func isSQLNull(ty reflect.Type) bool {
+	return ty.PkgPath() == "database/sql" &&
+		strings.HasPrefix(ty.Name(), "Null") &&
+		ty.Kind() == reflect.Struct &&
+		ty.NumField() == 2 &&
+		ty.Field(1).Name == "Valid" &&
+		ty.Field(1).Type.Kind() == reflect.Bool
+}
+
+// isNullable reports whether an empty cell should decode as a null value of ty
+func isNullable(ty reflect.Type) bool {
+	return ty.Kind() == reflect.Ptr || ty.Kind() == reflect.Slice || reflect.PtrTo(ty).Implements(sqlScanner)
+}
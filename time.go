@@ -0,0 +1,221 @@
+package csv
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special time formats that can be used in place of a layout
+const (
+	formatUnix      = "unix"      // seconds since the Unix epoch
+	formatUnixMilli = "unixmilli" // milliseconds since the Unix epoch
+	formatExcel     = "excel"     // Excel serial day number, days since 1899-12-30
+)
+
+// layoutNames are the layouts of the time package that can be named in the "format" tag option,
+// as several of them contain commas which can't be written within a tag option
+var layoutNames = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// minUnix and maxUnix bound the seconds since the Unix epoch accepted by the unix, unixmilli and excel formats.
+// They cover years 0 to 9999, the range that time.Time can format as RFC 3339
+var (
+	minUnix = time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	maxUnix = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC).Unix()
+)
+
+// excelEpochDays is the Excel serial day number of the Unix epoch
+const excelEpochDays = 25569
+
+// parseTime parses value using each of the layouts in turn, returning the first that succeeds.
+// Times without a zone are parsed in loc, or UTC if loc is nil, see time.Parse
+func parseTime(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	var firstErr error
+	for _, layout := range layouts {
+		t, err := parseLayout(value, layout, loc)
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if len(layouts) == 1 {
+		return time.Time{}, firstErr
+	}
+	return time.Time{}, fmt.Errorf("parsing time %q: does not match any of the formats %q", value, layouts)
+}
+
+func parseLayout(value, layout string, loc *time.Location) (time.Time, error) {
+	switch layout {
+	case formatUnix:
+		if !strings.ContainsAny(value, ".eE") {
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if sec < minUnix || sec > maxUnix {
+				return time.Time{}, rangeError("ParseInt", value)
+			}
+			return inLocation(time.Unix(sec, 0), loc), nil
+		}
+
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !(f >= float64(minUnix) && f < float64(maxUnix+1)) {
+			return time.Time{}, rangeError("ParseFloat", value)
+		}
+		sec, frac := math.Modf(f)
+		return inLocation(time.Unix(int64(sec), int64(math.Round(frac*1e9))), loc), nil
+	case formatUnixMilli:
+		msec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if msec < minUnix*1000 || msec > maxUnix*1000+999 {
+			return time.Time{}, rangeError("ParseInt", value)
+		}
+		return inLocation(time.UnixMilli(msec), loc), nil
+	case formatExcel:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if sec := (f - excelEpochDays) * 24 * 60 * 60; !(sec >= float64(minUnix) && sec < float64(maxUnix+1)) {
+			return time.Time{}, rangeError("ParseFloat", value)
+		}
+		if loc == nil {
+			loc = time.UTC
+		}
+		// Excel serials are wall clock times, so they are built from the date rather than by adding durations.
+		// Fractions of a day are rounded to the millisecond, as that is all the precision Excel keeps
+		days, frac := math.Modf(f)
+		if frac < 0 {
+			days, frac = days-1, frac+1
+		}
+		nsec := time.Duration(math.Round(frac * float64(24*time.Hour))).Round(time.Millisecond)
+		return time.Date(1899, 12, 30+int(days), 0, 0, 0, int(nsec), loc), nil
+	}
+
+	if loc == nil {
+		return time.Parse(layout, value)
+	}
+	return time.ParseInLocation(layout, value, loc)
+}
+
+// inLocation returns t in loc, or UTC if loc is nil
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// formatTime formats t using layout, after converting it into loc if it is not nil
+func formatTime(t time.Time, layout string, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	switch layout {
+	case formatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case formatUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case formatExcel:
+		year, month, day := t.Date()
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		days := (date.Unix() - time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Unix()) / (24 * 60 * 60)
+		clock := time.Duration(t.Hour())*time.Hour +
+			time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second +
+			time.Duration(t.Nanosecond()).Round(time.Millisecond)
+		return strconv.FormatFloat(float64(days)+float64(clock)/float64(24*time.Hour), 'f', -1, 64)
+	}
+
+	return t.Format(layout)
+}
+
+// durationUnits are the units accepted by the "unit" tag option for time.Duration fields
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// parseDuration parses value as a plain number of units, or using time.ParseDuration if unit is 0
+func parseDuration(value string, unit time.Duration) (time.Duration, error) {
+	if unit == 0 {
+		return time.ParseDuration(value)
+	}
+
+	if !strings.ContainsAny(value, ".eE") {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+				return 0, rangeError("ParseInt", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	d := math.Round(f * float64(unit))
+	if !inInt64Range(d) {
+		return 0, rangeError("ParseFloat", value)
+	}
+	return time.Duration(d), nil
+}
+
+// inInt64Range reports whether f can be converted to an int64 without overflowing
+func inInt64Range(f float64) bool {
+	return f >= math.MinInt64 && f < -math.MinInt64
+}
+
+// rangeError returns the error a strconv function fn would give for value being out of range
+func rangeError(fn, value string) error {
+	return &strconv.NumError{Func: fn, Num: value, Err: strconv.ErrRange}
+}
+
+// formatDuration formats d as a plain number of units, or using time.Duration.String if unit is 0
+func formatDuration(d time.Duration, unit time.Duration) string {
+	if unit == 0 {
+		return d.String()
+	}
+
+	if d%unit == 0 {
+		return strconv.FormatInt(int64(d/unit), 10)
+	}
+	return strconv.FormatFloat(float64(d)/float64(unit), 'f', -1, 64)
+}
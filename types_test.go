@@ -2,8 +2,15 @@ package csv
 
 import (
 	"bytes"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -188,7 +195,7 @@ func TestDecodeFailInt(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailInt8(t *testing.T) {
@@ -199,7 +206,7 @@ func TestDecodeFailInt8(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailInt16(t *testing.T) {
@@ -210,7 +217,7 @@ func TestDecodeFailInt16(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailInt32(t *testing.T) {
@@ -221,7 +228,7 @@ func TestDecodeFailInt32(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailInt64(t *testing.T) {
@@ -232,7 +239,7 @@ func TestDecodeFailInt64(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseInt: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailUint(t *testing.T) {
@@ -243,7 +250,7 @@ func TestDecodeFailUint(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseUint: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailUint8(t *testing.T) {
@@ -254,7 +261,7 @@ func TestDecodeFailUint8(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseUint: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailUint16(t *testing.T) {
@@ -265,7 +272,7 @@ func TestDecodeFailUint16(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseUint: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailUint32(t *testing.T) {
@@ -276,7 +283,7 @@ func TestDecodeFailUint32(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseUint: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailUint64(t *testing.T) {
@@ -287,7 +294,7 @@ func TestDecodeFailUint64(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseUint: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseUint: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailBool(t *testing.T) {
@@ -298,7 +305,7 @@ func TestDecodeFailBool(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseBool: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseBool: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailFloat32(t *testing.T) {
@@ -309,7 +316,7 @@ func TestDecodeFailFloat32(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseFloat: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseFloat: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailFloat64(t *testing.T) {
@@ -320,7 +327,7 @@ func TestDecodeFailFloat64(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "strconv.ParseFloat: parsing \"a\": invalid syntax")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseFloat: parsing \"a\": invalid syntax")
 }
 
 func TestDecodeFailTime(t *testing.T) {
@@ -331,5 +338,1554 @@ func TestDecodeFailTime(t *testing.T) {
 
 	output := []Data{}
 	err := Unmarshal([]byte(data), &output)
-	assert.EqualError(t, err, "parsing time \"a\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"a\" as \"2006\"")
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: parsing time \"a\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"a\" as \"2006\"")
+}
+
+type TagOptions struct {
+	Name     string    `csv:"name"`
+	Internal string    `csv:"-"`
+	Dash     string    `csv:"-,"`
+	Count    int       `csv:"count,omitempty"`
+	Ratio    float64   `csv:"ratio,default=0.5"`
+	Status   string    `csv:"status,omitempty,default=active"`
+	Skipped  NoMarshal `csv:"-"`
+}
+
+func TestTagOptions(t *testing.T) {
+	input := []TagOptions{
+		{Name: "a", Internal: "secret", Dash: "dash", Count: 1, Ratio: 0.25, Status: "inactive"},
+		{Name: "b", Internal: "secret", Ratio: 1},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `name,-,count,ratio,status
+a,dash,1,0.250000000000000,inactive
+b,,,1.000000000000000,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []TagOptions{}
+	err = Unmarshal([]byte(`name,-,count,ratio,status
+a,dash,1,0.25,inactive
+b,,0,,`), &output2)
+	assert.Nil(t, err)
+
+	expectedOutput2 := []TagOptions{
+		{Name: "a", Dash: "dash", Count: 1, Ratio: 0.25, Status: "inactive"},
+		{Name: "b", Ratio: 0.5, Status: "active"},
+	}
+	assert.Equal(t, expectedOutput2, output2)
+}
+
+func TestDecodeFailDefault(t *testing.T) {
+	type Data struct {
+		A int `csv:"A,default=none"`
+	}
+	data := "A\n\"\""
+
+	output := []Data{}
+	err := Unmarshal([]byte(data), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"none\": invalid syntax")
+}
+
+type Unexported struct {
+	A     string
+	b     int
+	cache map[string]NoMarshal
+	B     int
+}
+
+func TestUnexportedFields(t *testing.T) {
+	input := []Unexported{
+		{A: "a", b: 1, cache: map[string]NoMarshal{}, B: 2},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\na,2\n", string(output1))
+
+	output2 := []Unexported{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Unexported{{A: "a", B: 2}}, output2)
+}
+
+func TestUnexportedFieldsDisallowUnknownColumns(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader([]byte("A,b,B\na,1,2")))
+	decoder.DisallowUnknownColumns()
+	output := []Unexported{}
+	err := decoder.Decode(&output)
+	assert.EqualError(t, err, "Decode: field for header[b] was not found")
+}
+
+type Pointers struct {
+	A *string
+	B *int64
+	C *time.Time
+	D *Custom
+}
+
+func TestPointerFields(t *testing.T) {
+	a := "a"
+	b := int64(-1)
+	c := time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC)
+	input := []Pointers{
+		{A: &a, B: &b, C: &c, D: &Custom{A: "value", B: 1}},
+		{},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `A,B,C,D
+a,-1,2020-07-03T16:39:44Z,value|1
+,,,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Pointers{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestPointerFieldsNullTokens(t *testing.T) {
+	data := `A,B,C,D
+NULL,\N,NULL,\N
+a,1,,value|1
+`
+
+	decoder := NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.SetNullTokens("NULL", `\N`)
+	output := []Pointers{}
+	err := decoder.Decode(&output)
+	assert.Nil(t, err)
+
+	a := "a"
+	b := int64(1)
+	assert.Equal(t, []Pointers{
+		{},
+		{A: &a, B: &b, D: &Custom{A: "value", B: 1}},
+	}, output)
+}
+
+func TestPointerFieldsFail(t *testing.T) {
+	type Data struct {
+		A *int
+	}
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A\nNULL")))
+	output := []Data{}
+	err := decoder.Decode(&output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"NULL\": invalid syntax")
+}
+
+// Level implements both the text and csv interfaces, the csv ones should be used
+type Level int
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte("text"), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	return errors.New("UnmarshalText should not be called")
+}
+
+func (l Level) MarshalCSV() string {
+	return strings.Repeat("*", int(l))
+}
+
+func (l *Level) UnmarshalCSV(value string) error {
+	*l = Level(len(value))
+	return nil
+}
+
+// Color only implements the text interfaces, which take priority over its kind
+type Color uint8
+
+func (c Color) MarshalText() ([]byte, error) {
+	switch c {
+	case 0:
+		return []byte("red"), nil
+	case 1:
+		return []byte("green"), nil
+	}
+	return nil, fmt.Errorf("unknown color %d", uint8(c))
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = 0
+	case "green":
+		*c = 1
+	default:
+		return fmt.Errorf("unknown color %q", text)
+	}
+	return nil
+}
+
+type TextTypes struct {
+	IP    net.IP
+	Int   big.Int
+	IntP  *big.Int
+	Level Level
+	Color Color
+}
+
+func TestTextTypes(t *testing.T) {
+	input := []TextTypes{
+		{
+			IP:    net.IPv4(192, 168, 0, 1),
+			Int:   *big.NewInt(0).Lsh(big.NewInt(1), 100),
+			IntP:  big.NewInt(-42),
+			Level: 3,
+			Color: 1,
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `IP,Int,IntP,Level,Color
+192.168.0.1,1267650600228229401496703205376,-42,***,green
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []TextTypes{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestTextTypesFail(t *testing.T) {
+	b, err := Marshal([]TextTypes{{Color: 2}})
+	assert.EqualError(t, err, "Encode: row 0, column 5: could not encode field Color into header[Color]: unknown color 2")
+	assert.Empty(t, b)
+
+	output := []TextTypes{}
+	err = Unmarshal([]byte("Color\nblue"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[Color] into field Color: unknown color \"blue\"")
+}
+
+// Fallible can fail to encode itself
+type Fallible struct {
+	Value string
+}
+
+func (f Fallible) MarshalCSV() (string, error) {
+	if f.Value == "" {
+		return "", errors.New("empty value")
+	}
+	return f.Value, nil
+}
+
+func TestMarshalCSVWithError(t *testing.T) {
+	type Data struct {
+		A string
+		B Fallible `csv:"b"`
+	}
+
+	output, err := Marshal([]Data{{A: "a", B: Fallible{"b"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "A,b\na,b\n", string(output))
+
+	_, err = Marshal([]Data{{A: "a", B: Fallible{"b"}}, {A: "c"}})
+	assert.EqualError(t, err, "Encode: row 1, column 2: could not encode field B into header[b]: empty value")
+
+	var encodeErr *EncodeError
+	if assert.True(t, errors.As(err, &encodeErr)) {
+		assert.Equal(t, 1, encodeErr.Row)
+		assert.Equal(t, 2, encodeErr.Column)
+		assert.Equal(t, "b", encodeErr.Header)
+		assert.Equal(t, "B", encodeErr.Field)
+		assert.EqualError(t, encodeErr.Err, "empty value")
+	}
+}
+
+type TimeFormats struct {
+	Default time.Time
+	Date    time.Time  `csv:"date,format=2006-01-02"`
+	Mixed   time.Time  `csv:"mixed,format=2006-01-02 15:04|02/01/2006"`
+	Pointer *time.Time `csv:"pointer,format=15:04:05"`
+}
+
+func TestTimeFormats(t *testing.T) {
+	pointer := time.Date(0, 1, 1, 12, 30, 0, 0, time.UTC)
+	input := []TimeFormats{
+		{
+			Default: time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC),
+			Date:    time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC),
+			Mixed:   time.Date(2020, 07, 03, 16, 39, 0, 0, time.UTC),
+			Pointer: &pointer,
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `Default,date,mixed,pointer
+2020-07-03T16:39:44Z,2020-07-03,2020-07-03 16:39,12:30:00
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []TimeFormats{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+
+	output3 := []TimeFormats{}
+	err = Unmarshal([]byte("mixed\n03/07/2020"), &output3)
+	assert.Nil(t, err)
+	assert.Equal(t, []TimeFormats{{Mixed: time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC)}}, output3)
+}
+
+func TestTimeFormatsFail(t *testing.T) {
+	output := []TimeFormats{}
+	err := Unmarshal([]byte("mixed\n2020-07-03"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[mixed] into field Mixed: parsing time \"2020-07-03\": does not match any of the formats [\"2006-01-02 15:04\" \"02/01/2006\"]")
+
+	err = Unmarshal([]byte("date\n03/07/2020"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[date] into field Date: parsing time \"03/07/2020\" as \"2006-01-02\": cannot parse \"03/07/2020\" as \"2006\"")
+
+	// the comma ends the option, so the layout can't be used from a tag
+	type Comma struct {
+		A time.Time `csv:"A,format=Jan 2, 2006"`
+	}
+	err = Unmarshal([]byte("A\nJul 3, 2020"), &[]Comma{})
+	assert.EqualError(t, err, "Decode: field A: unknown tag option \" 2006\", option values can't contain commas")
+
+	type Unknown struct {
+		A int `csv:"A,requried"`
+	}
+	_, err = Marshal([]Unknown{{}})
+	assert.EqualError(t, err, "Encode: field A: unknown tag option \"requried\", option values can't contain commas")
+}
+
+func TestTimeFormatNames(t *testing.T) {
+	type Data struct {
+		A time.Time `csv:"A,format=RFC1123"`
+		B time.Time `csv:"B,format=DateOnly|Kitchen"`
+	}
+
+	input := []Data{
+		{
+			A: time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC),
+			B: time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\n\"Fri, 03 Jul 2020 16:39:44 UTC\",2020-07-03\n", string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+
+	// layouts with commas can still be set on the Decoder
+	decoder := NewDecoder(strings.NewReader("A\n\"Jul 3, 2020\""))
+	decoder.SetTimeFormat("Jan 2, 2006")
+	output3 := []struct{ A time.Time }{}
+	err = decoder.Decode(&output3)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC), output3[0].A)
+}
+
+func TestTimeFormatDefaults(t *testing.T) {
+	type Data struct {
+		A time.Time
+		B time.Time `csv:"B,format=2006"`
+	}
+
+	input := []Data{
+		{
+			A: time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC),
+			B: time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetTimeFormat("02 Jan 2006")
+	err := encoder.Encode(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\n03 Jul 2020,2021\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A,B\n03 Jul 2020,2021\n2020-07-03,2021")))
+	decoder.SetTimeFormat("02 Jan 2006", "2006-01-02")
+	output := []Data{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{input[0], input[0]}, output)
+}
+
+func TestTimeLocation(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if !assert.Nil(t, err) {
+		return
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	type Data struct {
+		A time.Time `csv:"A,format=2006-01-02 15:04"`
+		B time.Time `csv:"B,format=2006-01-02 15:04,loc=Asia/Tokyo"`
+	}
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A,B\n2020-07-03 16:39,2020-07-03 16:39")))
+	decoder.SetLocation(london)
+	output := []Data{}
+	err = decoder.Decode(&output)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, time.Date(2020, 07, 03, 15, 39, 0, 0, time.UTC).Equal(output[0].A))
+	assert.Equal(t, london, output[0].A.Location())
+	assert.True(t, time.Date(2020, 07, 03, 7, 39, 0, 0, time.UTC).Equal(output[0].B))
+	assert.Equal(t, tokyo, output[0].B.Location())
+
+	input := []Data{
+		{
+			A: time.Date(2020, 07, 03, 15, 39, 0, 0, time.UTC),
+			B: time.Date(2020, 07, 03, 7, 39, 0, 0, time.UTC),
+		},
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetLocation(london)
+	err = encoder.Encode(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\n2020-07-03 16:39,2020-07-03 16:39\n", buf.String())
+}
+
+func TestTimeLocationFail(t *testing.T) {
+	type Data struct {
+		A time.Time `csv:"A,loc=Nowhere/Special"`
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A\n2020-07-03T16:39:44Z"), &output)
+	assert.EqualError(t, err, "Decode: field A: unknown time zone Nowhere/Special")
+
+	b, err := Marshal([]Data{{}})
+	assert.EqualError(t, err, "Encode: field A: unknown time zone Nowhere/Special")
+	assert.Empty(t, b)
+}
+
+func TestTimeEpochFormats(t *testing.T) {
+	type Data struct {
+		Unix      time.Time `csv:"unix,format=unix"`
+		UnixMilli time.Time `csv:"unixmilli,format=unixmilli"`
+		Excel     time.Time `csv:"excel,format=excel"`
+	}
+
+	input := []Data{
+		{
+			Unix:      time.Date(2021, 01, 01, 12, 0, 0, 0, time.UTC),
+			UnixMilli: time.Date(2021, 01, 01, 12, 0, 0, 123000000, time.UTC),
+			Excel:     time.Date(2021, 01, 01, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Unix:      time.Date(1970, 01, 01, 0, 0, 0, 0, time.UTC),
+			UnixMilli: time.Date(1969, 12, 31, 23, 59, 59, 999000000, time.UTC),
+			Excel:     time.Date(1900, 03, 01, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `unix,unixmilli,excel
+1609502400,1609502400123,44197.5
+0,-1,61.333333333333336
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+
+	output3 := []Data{}
+	err = Unmarshal([]byte("unix\n1609502400.25"), &output3)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Unix: time.Date(2021, 01, 01, 12, 0, 0, 250000000, time.UTC)}}, output3)
+}
+
+func TestTimeEpochFormatsLocation(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	type Data struct {
+		Unix  time.Time `csv:"unix,format=unix|2006-01-02"`
+		Excel time.Time `csv:"excel,format=excel"`
+	}
+
+	decoder := NewDecoder(bytes.NewReader([]byte("unix,excel\n1593790784,44015.75\n2020-07-03,44015")))
+	decoder.SetLocation(london)
+	output := []Data{}
+	err = decoder.Decode(&output)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, []Data{
+		{
+			Unix:  time.Date(2020, 07, 03, 16, 39, 44, 0, london),
+			Excel: time.Date(2020, 07, 03, 18, 0, 0, 0, london),
+		},
+		{
+			Unix:  time.Date(2020, 07, 03, 0, 0, 0, 0, london),
+			Excel: time.Date(2020, 07, 03, 0, 0, 0, 0, london),
+		},
+	}, output)
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetLocation(london)
+	err = encoder.Encode([]Data{{
+		Unix:  time.Date(2020, 07, 03, 15, 39, 44, 0, time.UTC),
+		Excel: time.Date(2020, 07, 03, 17, 0, 0, 0, time.UTC),
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "unix,excel\n1593790784,44015.75\n", buf.String())
+}
+
+func TestTimeEpochFormatsFail(t *testing.T) {
+	type Data struct {
+		Unix      time.Time `csv:"unix,format=unix"`
+		UnixMilli time.Time `csv:"unixmilli,format=unixmilli"`
+		Excel     time.Time `csv:"excel,format=excel"`
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("unix\ntoday"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[unix] into field Unix: strconv.ParseInt: parsing \"today\": invalid syntax")
+
+	err = Unmarshal([]byte("unixmilli\n1.5"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[unixmilli] into field UnixMilli: strconv.ParseInt: parsing \"1.5\": invalid syntax")
+
+	err = Unmarshal([]byte("excel\nmonday"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[excel] into field Excel: strconv.ParseFloat: parsing \"monday\": invalid syntax")
+
+	// times outside of years 0 to 9999 are rejected
+	err = Unmarshal([]byte("unix\n1e30"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[unix] into field Unix: strconv.ParseFloat: parsing \"1e30\": value out of range")
+
+	err = Unmarshal([]byte("unix\n253402300800"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[unix] into field Unix: strconv.ParseInt: parsing \"253402300800\": value out of range")
+
+	err = Unmarshal([]byte("unixmilli\n-62167219200001"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[unixmilli] into field UnixMilli: strconv.ParseInt: parsing \"-62167219200001\": value out of range")
+
+	err = Unmarshal([]byte("excel\n1e300"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[excel] into field Excel: strconv.ParseFloat: parsing \"1e300\": value out of range")
+
+	err = Unmarshal([]byte("unix,excel\n253402300799,2958465.5"), &output)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), output[0].Unix)
+	assert.Equal(t, time.Date(9999, 12, 31, 12, 0, 0, 0, time.UTC), output[0].Excel)
+}
+
+func TestDurations(t *testing.T) {
+	type Data struct {
+		A time.Duration
+		B time.Duration  `csv:"B,unit=s"`
+		C *time.Duration `csv:"C,unit=ms"`
+	}
+
+	c := 1500 * time.Microsecond
+	input := []Data{
+		{A: 90 * time.Minute, B: 90 * time.Minute, C: &c},
+		{A: 1500 * time.Millisecond, B: 1500 * time.Millisecond},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `A,B,C
+1h30m0s,5400,1.5
+1.5s,1.5,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestDurationsFail(t *testing.T) {
+	type Data struct {
+		A time.Duration
+		B time.Duration `csv:"B,unit=s"`
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A\n90"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: time: missing unit in duration \"90\"")
+
+	err = Unmarshal([]byte("B\n1h"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[B] into field B: strconv.ParseFloat: parsing \"1h\": invalid syntax")
+
+	type Hours struct {
+		A time.Duration `csv:"A,unit=h"`
+	}
+
+	err = Unmarshal([]byte("A\n9999999999"), &[]Hours{})
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"9999999999\": value out of range")
+
+	err = Unmarshal([]byte("A\n-2562048"), &[]Hours{})
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"-2562048\": value out of range")
+
+	err = Unmarshal([]byte("A\n9999999999.5"), &[]Hours{})
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseFloat: parsing \"9999999999.5\": value out of range")
+
+	err = Unmarshal([]byte("A\nNaN"), &[]Hours{})
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseFloat: parsing \"NaN\": value out of range")
+
+	hours := []Hours{}
+	err = Unmarshal([]byte("A\n2562047"), &hours)
+	assert.Nil(t, err)
+	assert.Equal(t, []Hours{{A: 2562047 * time.Hour}}, hours)
+
+	type BadUnit struct {
+		A time.Duration `csv:"A,unit=days"`
+	}
+
+	err = Unmarshal([]byte("A\n1"), &[]BadUnit{})
+	assert.EqualError(t, err, "Decode: field A: unknown duration unit \"days\"")
+}
+
+func TestFloatFormat(t *testing.T) {
+	type Data struct {
+		A float64
+		B float32
+		C float64 `csv:"C,prec=2"`
+		D float64 `csv:"D,fmt=e"`
+		E float64 `csv:"E,fmt=e,prec=3"`
+	}
+
+	input := []Data{
+		{A: 0.1, B: 0.1, C: 1234.5678, D: 1e21, E: math.Pi},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B,C,D,E\n0.100000000000000,0.100000,1234.57,1e+21,3.142e+00\n", string(output1))
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetFloatFormat('g', -1)
+	err = encoder.Encode(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B,C,D,E\n0.1,0.1,1234.57,1e+21,3.142e+00\n", buf.String())
+
+	output2 := []Data{}
+	err = Unmarshal([]byte(buf.String()), &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.1, output2[0].A)
+	assert.Equal(t, float32(0.1), output2[0].B)
+}
+
+func TestFloatFormatFail(t *testing.T) {
+	type BadFmt struct {
+		A float64 `csv:"A,fmt=z"`
+	}
+	_, err := Marshal([]BadFmt{{}})
+	assert.EqualError(t, err, "Encode: field A: unknown float format \"z\"")
+
+	type BadPrec struct {
+		A float64 `csv:"A,prec=two"`
+	}
+	_, err = Marshal([]BadPrec{{}})
+	assert.EqualError(t, err, "Encode: field A: invalid float precision \"two\"")
+
+	encoder := NewEncoder(&bytes.Buffer{})
+	encoder.SetFloatFormat('z', 2)
+	err = encoder.Encode([]struct{ A float64 }{{A: 1.5}})
+	assert.EqualError(t, err, "Encode: unknown float format \"z\"")
+
+	encoder.SetFloatFormat('f', -2)
+	err = encoder.Encode([]struct{ A float64 }{{A: 1.5}})
+	assert.EqualError(t, err, "Encode: invalid float precision -2")
+}
+
+func TestNumberFormat(t *testing.T) {
+	type Data struct {
+		A float64 `csv:"A,prec=2"`
+		B int
+		C float64 `csv:"C,decimal=dot,group=comma,prec=1"`
+		D uint
+	}
+
+	input := []Data{
+		{A: 1234.56, B: -1234567, C: 1234567.8, D: 123},
+		{A: -0.5, B: 12, C: -1000, D: 1000},
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetDelimiter(';')
+	encoder.SetNumberFormat(',', '.')
+	err := encoder.Encode(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A;B;C;D\n1.234,56;-1.234.567;1,234,567.8;123\n-0,50;12;-1,000.0;1.000\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetDelimiter(';')
+	decoder.SetNumberFormat(',', '.')
+	output := []Data{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestNumberFormatAccounting(t *testing.T) {
+	type Data struct {
+		Amount float64
+		Count  int
+	}
+
+	data := "Amount;Count\n(1 234,56 €); 1 000\n€ 12,5;(3)\n-£7;+4"
+
+	decoder := NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.SetDelimiter(';')
+	decoder.SetNumberFormat(',', ' ')
+	output := []Data{}
+	err := decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{
+		{Amount: -1234.56, Count: 1000},
+		{Amount: 12.5, Count: -3},
+		{Amount: -7, Count: 4},
+	}, output)
+}
+
+func TestNumberFormatFail(t *testing.T) {
+	type Data struct {
+		A float64 `csv:"A,decimal=comma"`
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A\n\"1,2,3\""), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseFloat: parsing \"1.2.3\": invalid syntax")
+
+	type BadSeparator struct {
+		A float64 `csv:"A,group=thin"`
+	}
+	err = Unmarshal([]byte("A\n1"), &[]BadSeparator{})
+	assert.EqualError(t, err, "Decode: field A: unknown separator \"thin\"")
+
+	type SameSeparator struct {
+		A float64 `csv:"A,group=dot"`
+	}
+	err = Unmarshal([]byte("A\n1.5"), &[]SameSeparator{})
+	assert.EqualError(t, err, "Decode: field A: decimal and grouping separators are both '.'")
+
+	decoder := NewDecoder(strings.NewReader("A\n1.5"))
+	decoder.SetNumberFormat('.', '.')
+	err = decoder.Decode(&[]struct{ A float64 }{})
+	assert.EqualError(t, err, "Decode: decimal and grouping separators are both '.'")
+
+	encoder := NewEncoder(&bytes.Buffer{})
+	encoder.SetNumberFormat(',', ',')
+	err = encoder.Encode([]struct{ A float64 }{{A: 1.5}})
+	assert.EqualError(t, err, "Encode: decimal and grouping separators are both ','")
+}
+
+func TestBoolTokens(t *testing.T) {
+	type Data struct {
+		A bool
+		B bool  `csv:"B,bool=Y|N"`
+		C bool  `csv:"C,bool=x|"`
+		D *bool `csv:"D,bool=on|off"`
+	}
+
+	on := true
+	input := []Data{
+		{A: true, B: true, C: true, D: &on},
+		{},
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetBoolTokens([]string{"yes", "y"}, []string{"no", "n"})
+	err := encoder.Encode(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B,C,D\nyes,Y,x,on\nno,N,,\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A,B,C,D\nyes,Y,x,on\nN,n,,\nY,y,X,OFF")))
+	decoder.SetBoolTokens([]string{"yes", "y"}, []string{"no", "n"})
+	output := []Data{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+
+	off := false
+	assert.Equal(t, []Data{input[0], input[1], {A: true, B: true, C: true, D: &off}}, output)
+}
+
+func TestBoolTokensFail(t *testing.T) {
+	type Data struct {
+		A bool `csv:"A,bool=Y|N"`
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A\ntrue"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: invalid boolean \"true\" - expected one of [\"Y\"] or [\"N\"]")
+
+	type BadTag struct {
+		A bool `csv:"A,bool=Y"`
+	}
+	_, err = Marshal([]BadTag{{}})
+	assert.EqualError(t, err, "Encode: field A: bool option \"Y\" must be a true and false token separated by '|'")
+}
+
+func TestNullTokens(t *testing.T) {
+	type Data struct {
+		A string
+		B int
+		C float64
+		D time.Time
+		E *int
+		F Custom
+	}
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A,B,C,D,E,F\nNULL,\\N,NA,#N/A,NULL,NA\na,1,1.5,2020-07-03T16:39:44Z,2,value|1")))
+	decoder.SetNullTokens("NULL", `\N`, "NA", "#N/A")
+	output := []Data{}
+	err := decoder.Decode(&output)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	e := 2
+	expected := []Data{
+		{},
+		{A: "a", B: 1, C: 1.5, D: time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC), E: &e, F: Custom{A: "value", B: 1}},
+	}
+	assert.Equal(t, expected, output)
+}
+
+func TestNullToken(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetNullToken(`\N`)
+	err := encoder.Encode([]Pointers{{}})
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B,C,D\n\\N,\\N,\\N,\\N\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetNullTokens(`\N`)
+	output := []Pointers{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Pointers{{}}, output)
+}
+
+func TestZeroEmpty(t *testing.T) {
+	type Data struct {
+		A int
+		B float64
+		C bool
+		D time.Time
+		E time.Duration
+		F int `csv:"F,default=7"`
+	}
+
+	decoder := NewDecoder(bytes.NewReader([]byte("A,B,C,D,E,F\n,,,,,\n1,1.5,true,2020-07-03T16:39:44Z,1s,1")))
+	decoder.ZeroEmpty()
+	output := []Data{}
+	err := decoder.Decode(&output)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	expected := []Data{
+		{F: 7},
+		{A: 1, B: 1.5, C: true, D: time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC), E: time.Second, F: 1},
+	}
+	assert.Equal(t, expected, output)
+
+	decoder = NewDecoder(bytes.NewReader([]byte("A,B,C,D,E,F\n,,,,,")))
+	decoder.ZeroEmpty()
+	decoder.DisableZeroEmpty()
+	err = decoder.Decode(&output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: strconv.ParseInt: parsing \"\": invalid syntax")
+}
+
+func TestZeroEmptyTag(t *testing.T) {
+	type Data struct {
+		A int     `csv:"A,zeroempty"`
+		B float64 `csv:"B,zeroempty"`
+		C int
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A,B,C\n,,1"), &output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{C: 1}}, output)
+
+	err = Unmarshal([]byte("A,B,C\n1,1,"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 3: could not decode header[C] into field C: strconv.ParseInt: parsing \"\": invalid syntax")
+}
+
+// Money is stored in the database as a number of cents
+type Money struct {
+	Cents int64
+}
+
+func (m *Money) Scan(src interface{}) error {
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	m.Cents = int64(math.Round(f * 100))
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	if m.Cents < 0 {
+		return nil, errors.New("negative money")
+	}
+	return float64(m.Cents) / 100, nil
+}
+
+type SQLTypes struct {
+	String  sql.NullString
+	Int64   sql.NullInt64
+	Float64 sql.NullFloat64 `csv:"Float64,prec=2"`
+	Bool    sql.NullBool    `csv:"Bool,bool=Y|N"`
+	Time    sql.NullTime    `csv:"Time,format=2006-01-02"`
+	Money   Money
+}
+
+func TestSQLTypes(t *testing.T) {
+	input := []SQLTypes{
+		{
+			String:  sql.NullString{String: "a", Valid: true},
+			Int64:   sql.NullInt64{Int64: 1, Valid: true},
+			Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+			Bool:    sql.NullBool{Bool: true, Valid: true},
+			Time:    sql.NullTime{Time: time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC), Valid: true},
+			Money:   Money{Cents: 1234},
+		},
+		{},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `String,Int64,Float64,Bool,Time,Money
+a,1,1.50,Y,2020-07-03,12.340000000000000
+,,,,,0.000000000000000
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []SQLTypes{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestSQLTypesNullTokens(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetNullToken("NULL")
+	err := encoder.Encode([]SQLTypes{{}})
+	assert.Nil(t, err)
+	assert.Equal(t, "String,Int64,Float64,Bool,Time,Money\nNULL,NULL,NULL,NULL,NULL,0.000000000000000\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetNullTokens("NULL")
+	output := []SQLTypes{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []SQLTypes{{}}, output)
+}
+
+func TestSQLTypesFail(t *testing.T) {
+	output := []SQLTypes{}
+	err := Unmarshal([]byte("Int64\none"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[Int64] into field Int64: strconv.ParseInt: parsing \"one\": invalid syntax")
+
+	err = Unmarshal([]byte("Money\nfree"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[Money] into field Money: strconv.ParseFloat: parsing \"free\": invalid syntax")
+
+	_, err = Marshal([]SQLTypes{{Money: Money{Cents: -1}}})
+	assert.EqualError(t, err, "Encode: row 0, column 6: could not encode field Money into header[Money]: negative money")
+}
+
+type Audit struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time `csv:"updated"`
+}
+
+type Owner struct {
+	Name string `csv:"owner"`
+}
+
+type named struct {
+	Name string
+	ID   int
+}
+
+type Embedded struct {
+	ID int
+	Audit
+	*Owner
+	named
+	Name string
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	created := time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC)
+	updated := time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC)
+
+	input := []Embedded{
+		{
+			ID:    1,
+			Audit: Audit{CreatedAt: created, UpdatedAt: updated},
+			Owner: &Owner{Name: "owner"},
+			Name:  "name",
+		},
+		{
+			ID:    2,
+			Audit: Audit{CreatedAt: created, UpdatedAt: updated},
+			Name:  "no owner",
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	// named.Name and named.ID are hidden by the shallower Name and ID fields
+	expectedOutput1 := `ID,CreatedAt,updated,owner,Name
+1,2020-07-03T16:39:44Z,2021-01-01T00:00:00Z,owner,name
+2,2020-07-03T16:39:44Z,2021-01-01T00:00:00Z,,no owner
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Embedded{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+
+	// the embedded pointer is left nil, as all of its cells are empty
+	assert.Equal(t, input, output2)
+}
+
+type Left struct {
+	A string
+	B string `csv:"B"`
+	C string
+}
+
+type Right struct {
+	A string
+	B string
+	C string `csv:"C"`
+}
+
+type Conflicting struct {
+	Left
+	Right
+}
+
+func TestEmbeddedStructsConflicts(t *testing.T) {
+	input := []Conflicting{
+		{
+			Left:  Left{A: "left a", B: "left b", C: "left c"},
+			Right: Right{A: "right a", B: "right b", C: "right c"},
+		},
+	}
+
+	// A is ambiguous so dropped, B and C are taken from the tagged field
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "B,C\nleft b,right c\n", string(output1))
+
+	output2 := []Conflicting{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Conflicting{{Left: Left{B: "left b"}, Right: Right{C: "right c"}}}, output2)
+}
+
+func TestEmbeddedStructsTagged(t *testing.T) {
+	type Data struct {
+		Custom `csv:"custom"`
+		Audit  `csv:"-"`
+	}
+
+	output1, err := Marshal([]Data{{Custom: Custom{A: "a", B: 1}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "custom\na|1\n", string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Custom: Custom{A: "a", B: 1}}}, output2)
+}
+
+func TestEmbeddedCellTypes(t *testing.T) {
+	type Data struct {
+		Custom
+		time.Time
+		sql.NullString
+		Name string
+	}
+
+	created := time.Date(2020, 07, 03, 16, 39, 44, 0, time.UTC)
+	input := []Data{
+		{
+			Custom:     Custom{A: "value1", B: 1},
+			Time:       created,
+			NullString: sql.NullString{String: "x", Valid: true},
+			Name:       "name",
+		},
+	}
+
+	// types that encode themselves as a single cell are columns, not flattened
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `Custom,Time,NullString,Name
+value1|1,2020-07-03T16:39:44Z,x,name
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+type lowerCell struct {
+	A string
+}
+
+func (c lowerCell) MarshalCSV() string {
+	return "cell:" + c.A
+}
+
+func (c *lowerCell) UnmarshalCSV(value string) error {
+	c.A = strings.TrimPrefix(value, "cell:")
+	return nil
+}
+
+func TestEmbeddedUnexportedCellType(t *testing.T) {
+	type Data struct {
+		lowerCell
+		B string
+	}
+	type Tagged struct {
+		lowerCell `csv:"cell"`
+		B         string
+	}
+
+	// the value of an unexported embedded type can't be read, so its fields are flattened instead
+	output1, err := Marshal([]Data{{lowerCell: lowerCell{A: "a"}, B: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\na,b\n", string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{lowerCell: lowerCell{A: "a"}, B: "b"}}, output2)
+
+	output3, err := Marshal([]Tagged{{lowerCell: lowerCell{A: "a"}, B: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "B\nb\n", string(output3))
+}
+
+func TestEmbeddedStructsFail(t *testing.T) {
+	output := []Embedded{}
+	err := Unmarshal([]byte("CreatedAt\nyesterday"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[CreatedAt] into field Audit.CreatedAt: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\"")
+}
+
+type Address struct {
+	Street string `csv:"street"`
+	City   string `csv:"city"`
+	Geo    struct {
+		Lat float64 `csv:"lat,prec=2"`
+		Lng float64 `csv:"lng,prec=2"`
+	} `csv:"geo,inline"`
+}
+
+type Customer struct {
+	Name     string   `csv:"name"`
+	Billing  Address  `csv:"billing,inline"`
+	Shipping *Address `csv:",inline"`
+}
+
+func TestInlineStructs(t *testing.T) {
+	billing := Address{Street: "1 Main St", City: "Springfield"}
+	billing.Geo.Lat = 1.5
+	billing.Geo.Lng = -2.25
+
+	input := []Customer{
+		{Name: "a", Billing: billing, Shipping: &Address{Street: "2 High St", City: "Shelbyville"}},
+		{Name: "b", Billing: billing},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `name,billing.street,billing.city,billing.geo.lat,billing.geo.lng,Shipping.street,Shipping.city,Shipping.geo.lat,Shipping.geo.lng
+a,1 Main St,Springfield,1.50,-2.25,2 High St,Shelbyville,0.00,0.00
+b,1 Main St,Springfield,1.50,-2.25,,,,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	// the empty cells of the nil Shipping address decode back to a nil pointer
+	output2 := []Customer{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetNullToken("NULL")
+	err = encoder.Encode(input)
+	assert.Nil(t, err)
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetNullTokens("NULL")
+	output4 := []Customer{}
+	err = decoder.Decode(&output4)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output4)
+
+	// a pointer is allocated as soon as one of its cells isn't empty
+	output3 := []Customer{}
+	err = Unmarshal([]byte("name,Shipping.street,Shipping.city\nc,,Shelbyville"), &output3)
+	assert.Nil(t, err)
+	assert.Equal(t, []Customer{{Name: "c", Shipping: &Address{City: "Shelbyville"}}}, output3)
+}
+
+func TestInlineStructsPrefixSeparator(t *testing.T) {
+	type Data struct {
+		Billing Address `csv:"billing,inline"`
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetPrefixSeparator("_")
+	err := encoder.Encode([]Data{{Billing: Address{Street: "1 Main St"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "billing_street,billing_city,billing_geo_lat,billing_geo_lng\n1 Main St,,0.00,0.00\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetPrefixSeparator("_")
+	output := []Data{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Billing: Address{Street: "1 Main St"}}}, output)
+}
+
+type Recursive struct {
+	Name string
+	Next *Recursive `csv:"next,inline"`
+}
+
+func TestInlineStructsFail(t *testing.T) {
+	_, err := Marshal([]Recursive{{}})
+	assert.EqualError(t, err, "Encode: field Next: cannot inline recursive type *csv.Recursive")
+
+	type NotStruct struct {
+		A int `csv:"a,inline"`
+	}
+	err = Unmarshal([]byte("a\n1"), &[]NotStruct{})
+	assert.EqualError(t, err, "Decode: field A: only structs can be inlined, not int")
+
+	type CellType struct {
+		T time.Time `csv:"t,inline"`
+	}
+	_, err = Marshal([]CellType{{}})
+	assert.EqualError(t, err, "Encode: field T: time.Time is encoded as a single cell and cannot be inlined")
+
+	type NoColumns struct {
+		A struct {
+			hidden int
+		} `csv:"a,inline"`
+	}
+	_, err = Marshal([]NoColumns{{}})
+	assert.EqualError(t, err, "Encode: field A: inlined type struct { hidden int } has no columns")
+
+	output := []Customer{}
+	err = Unmarshal([]byte("billing.geo.lat\nnorth"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[billing.geo.lat] into field Billing.Geo.Lat: strconv.ParseFloat: parsing \"north\": invalid syntax")
+}
+
+func TestListFields(t *testing.T) {
+	type Data struct {
+		Tags    []string
+		Scores  []int       `csv:"scores,sep=;"`
+		Times   []time.Time `csv:"times,sep=comma,format=2006-01-02"`
+		Customs []Custom    `csv:"customs"`
+		Empty   []float64
+	}
+
+	input := []Data{
+		{
+			Tags:    []string{"a", "b|c", `d\e`},
+			Scores:  []int{1, -2, 3},
+			Times:   []time.Time{time.Date(2020, 07, 03, 0, 0, 0, 0, time.UTC), time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC)},
+			Customs: []Custom{{A: "x", B: 1}, {A: "y", B: 2}},
+		},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `Tags,scores,times,customs,Empty
+a|b\|c|d\\e,1;-2;3,"2020-07-03,2021-01-01",x\|1|y\|2,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestListFieldsEmpty(t *testing.T) {
+	type Data struct {
+		Name string
+		A    []string
+	}
+
+	// nil, empty and single empty string lists are all written as an empty cell, which decodes as nil
+	output1, err := Marshal([]Data{{Name: "nil"}, {Name: "empty", A: []string{}}, {Name: "blank", A: []string{""}}, {Name: "blanks", A: []string{"", ""}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Name,A\nnil,\nempty,\nblank,\nblanks,|\n", string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Name: "nil"}, {Name: "empty"}, {Name: "blank"}, {Name: "blanks", A: []string{"", ""}}}, output2)
+}
+
+func TestByteSliceFields(t *testing.T) {
+	type Data struct {
+		A []byte
+		B [][]byte
+		C []byte
+	}
+
+	input := []Data{
+		{A: []byte("hi"), B: [][]byte{[]byte("a"), []byte("b|c")}},
+	}
+
+	// byte slices are written as text, not as a list of numbers
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B,C\nhi,a|b\\|c,\n", string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+}
+
+func TestListFieldsSeparator(t *testing.T) {
+	type Data struct {
+		A []int
+		B []int `csv:"B,sep=|"`
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetListSeparator(" ")
+	err := encoder.Encode([]Data{{A: []int{1, 2}, B: []int{3, 4}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "A,B\n1 2,3|4\n", buf.String())
+
+	decoder := NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.SetListSeparator(" ")
+	output := []Data{}
+	err = decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{A: []int{1, 2}, B: []int{3, 4}}}, output)
+}
+
+func TestListFieldsFail(t *testing.T) {
+	type Data struct {
+		A []int
+	}
+
+	output := []Data{}
+	err := Unmarshal([]byte("A\n1|two|3"), &output)
+	assert.EqualError(t, err, "Decode: line 2, column 1: could not decode header[A] into field A: item 1: strconv.ParseInt: parsing \"two\": invalid syntax")
+
+	type Nested struct {
+		A [][]int
+	}
+	err = Unmarshal([]byte("A\n1"), &[]Nested{})
+	assert.EqualError(t, err, "Decode: [][]int is not a valid field type - try implement UnmarshalCSV for it")
+
+	_, err = Marshal([]Nested{{}})
+	assert.EqualError(t, err, "Encode: [][]int is not a valid field type - try implement MarshalCSV for it")
+
+	_, err = Marshal([]TextTypes{{}, {}})
+	assert.Nil(t, err)
+
+	type Colors struct {
+		A []Color
+	}
+	_, err = Marshal([]Colors{{A: []Color{0, 5}}})
+	assert.EqualError(t, err, "Encode: row 0, column 1: could not encode field A into header[A]: item 1: unknown color 5")
+
+	type BadSep struct {
+		A []int `csv:"A,sep=\\"`
+	}
+	err = Unmarshal([]byte("A\n1"), &[]BadSep{})
+	assert.EqualError(t, err, "Decode: field A: invalid list separator \"\\\\\"")
+}
+
+func TestListFieldsInvalidSeparator(t *testing.T) {
+	type Data struct {
+		A []string
+	}
+
+	for _, sep := range []string{"", `\`, `|\`} {
+		encoder := NewEncoder(&bytes.Buffer{})
+		encoder.SetListSeparator(sep)
+		err := encoder.Encode([]Data{{A: []string{"a", "b"}}})
+		assert.EqualError(t, err, fmt.Sprintf("Encode: invalid list separator %q", sep))
+
+		decoder := NewDecoder(strings.NewReader("A\na|b"))
+		decoder.SetListSeparator(sep)
+		err = decoder.Decode(&[]Data{})
+		assert.EqualError(t, err, fmt.Sprintf("Decode: invalid list separator %q", sep))
+
+		err = decoder.DecodeRecord(&Data{})
+		assert.EqualError(t, err, fmt.Sprintf("Decode: invalid list separator %q", sep))
+	}
+}
+
+func TestColumnPatterns(t *testing.T) {
+	type Data struct {
+		Name   string
+		Scores []int     `csv:"score_*"`
+		Phones [2]string `csv:"phone*"`
+	}
+
+	input := []Data{
+		{Name: "a", Scores: []int{1, 2, 3}, Phones: [2]string{"555-1234", "555-5678"}},
+		{Name: "b", Scores: []int{4}, Phones: [2]string{"555-0000", ""}},
+		{Name: "c"},
+	}
+
+	output1, err := Marshal(input)
+	assert.Nil(t, err)
+
+	expectedOutput1 := `Name,score_1,score_2,score_3,phone1,phone2
+a,1,2,3,555-1234,555-5678
+b,4,,,555-0000,
+c,,,,,
+`
+	assert.Equal(t, expectedOutput1, string(output1))
+
+	output2 := []Data{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	assert.Equal(t, input, output2)
+
+	// columns are collected by their number, wherever they are in the header
+	output3 := []Data{}
+	err = Unmarshal([]byte("score_10,Name,score_2,phone1,other\n10,d,2,555-9999,x"), &output3)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{{Name: "d", Scores: []int{2, 10}, Phones: [2]string{"555-9999", ""}}}, output3)
+}
+
+func TestColumnPatternsRaggedRows(t *testing.T) {
+	type Data struct {
+		Name   string
+		Scores [3]int `csv:"score_*"`
+		Others []int  `csv:"other_*"`
+	}
+
+	decoder := NewDecoder(strings.NewReader("score_1,Name,other_2,score_2,other_1\n5,bob,7\n1,amy,2,3,4"))
+	decoder.AllowRaggedRows()
+	output := []Data{}
+	err := decoder.Decode(&output)
+	assert.Nil(t, err)
+	assert.Equal(t, []Data{
+		{Name: "bob", Scores: [3]int{5, 0, 0}, Others: []int{0, 7}},
+		{Name: "amy", Scores: [3]int{1, 3, 0}, Others: []int{4, 2}},
+	}, output)
+}
+
+func TestColumnPatternsEmpty(t *testing.T) {
+	type Scores struct {
+		Scores []int `csv:"score_*"`
+	}
+
+	// a column is written even when every slice is empty, so the output can be decoded again
+	output1, err := Marshal([]Scores{})
+	assert.Nil(t, err)
+	assert.Equal(t, "score_1\n", string(output1))
+
+	err = Unmarshal(output1, &[]Scores{})
+	assert.Nil(t, err)
+
+	type Player struct {
+		Name string
+		*Scores
+	}
+
+	output1, err = Marshal([]Player{{Name: "a", Scores: &Scores{}}, {Name: "b", Scores: &Scores{}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Name,score_1\na,\nb,\n", string(output1))
+
+	output2 := []Player{}
+	err = Unmarshal(output1, &output2)
+	assert.Nil(t, err)
+	// the embedded pointer only has empty cells, so it is left nil
+	assert.Equal(t, []Player{{Name: "a"}, {Name: "b"}}, output2)
+
+	// numbered columns within a nil embedded pointer are null, like any other column
+	buf := bytes.NewBuffer([]byte{})
+	encoder := NewEncoder(buf)
+	encoder.SetNullToken("NULL")
+	err = encoder.Encode([]Player{{Name: "a", Scores: &Scores{Scores: []int{1, 2}}}, {Name: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Name,score_1,score_2\na,1,2\nb,NULL,NULL\n", buf.String())
+}
+
+func TestColumnPatternsFail(t *testing.T) {
+	type NotSlice struct {
+		A int `csv:"a_*"`
+	}
+	err := Unmarshal([]byte("a_1\n1"), &[]NotSlice{})
+	assert.EqualError(t, err, "Decode: field A: column pattern \"a_*\" needs a slice or array, not int")
+
+	type Data struct {
+		Scores [2]int `csv:"score_*"`
+	}
+	err = Unmarshal([]byte("score_1,score_2,score_3\n1,2,3"), &[]Data{})
+	assert.EqualError(t, err, "Decode: found 3 columns for field Scores, but [2]int can only hold 2")
+
+	err = Unmarshal([]byte("score_1,score_2\n1,two"), &[]Data{})
+	assert.EqualError(t, err, "Decode: line 2, column 2: could not decode header[score_2] into field Scores: strconv.ParseInt: parsing \"two\": invalid syntax")
+
+	type Colors struct {
+		A []Color `csv:"color*"`
+	}
+	_, err = Marshal([]Colors{{A: []Color{0, 5}}})
+	assert.EqualError(t, err, "Encode: row 0, column 2: could not encode field A into header[color2]: unknown color 5")
 }